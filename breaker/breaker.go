@@ -0,0 +1,243 @@
+package heptane
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open rejects every call.
+	Open
+	// HalfOpen lets through a limited number of trial calls.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "Closed"
+	case Open:
+		return "Open"
+	case HalfOpen:
+		return "HalfOpen"
+	}
+	return "Unknown"
+}
+
+// Default values of the fields of Breaker.
+const (
+	DefaultWindow        = 10 * time.Second
+	DefaultMinCalls      = 10
+	DefaultErrorRate     = 0.5
+	DefaultSlowCallRate  = 0.5
+	DefaultOpenDuration  = 5 * time.Second
+	DefaultHalfOpenCalls = 1
+)
+
+// Breaker is a circuit breaker. The zero value is a Closed Breaker with the
+// default thresholds and without latency threshold. The fields must not be
+// changed after the first call.
+type Breaker struct {
+	// Window is the duration of the periods in which the calls are
+	// counted. Zero means DefaultWindow.
+	Window time.Duration
+	// MinCalls is the number of calls in a Window below which the Breaker
+	// does not open. Zero means DefaultMinCalls.
+	MinCalls int
+	// ErrorRate is the ratio of failed calls in a Window that opens the
+	// Breaker. Zero means DefaultErrorRate.
+	ErrorRate float64
+	// SlowCall is the duration above which a successful call is slow.
+	// Zero means that no call is slow.
+	SlowCall time.Duration
+	// SlowCallRate is the ratio of slow calls in a Window that opens the
+	// Breaker. Zero means DefaultSlowCallRate.
+	SlowCallRate float64
+	// OpenDuration is the time the Breaker stays Open before becoming
+	// HalfOpen. Zero means DefaultOpenDuration.
+	OpenDuration time.Duration
+	// HalfOpenCalls is the number of trial calls of a HalfOpen Breaker,
+	// all of them must succeed to close it. Zero means
+	// DefaultHalfOpenCalls.
+	HalfOpenCalls int
+	// StateChanges, if not nil, is called with every change of State. It
+	// must not call the Breaker.
+	StateChanges func(from, to State)
+	// Failure decides whether the error of a call is a failure of the
+	// backend. The other errors count as successful calls. Nil means
+	// every error, BackendFailure excludes the errors of the accesses.
+	Failure func(error) bool
+
+	m       sync.Mutex
+	state   State
+	since   time.Time
+	calls   int
+	failed  int
+	slow    int
+	trials  int
+	trialed int
+	now     func() time.Time
+}
+
+// BackendFailure is a classifier for Failure. It reports false for the errors
+// caused by the accesses themselves: invalid Tables, duplicate keys and
+// missing rows.
+func BackendFailure(err error) bool {
+	if tve := (r.TableValidationError{}); errors.As(err, &tve) {
+		return false
+	}
+	return !errors.Is(err, r.ErrDuplicateKey) && !errors.Is(err, r.ErrNotFound)
+}
+
+// failure reports whether the error is a failure according to Failure.
+func (b *Breaker) failure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if b.Failure == nil {
+		return true
+	}
+	return b.Failure(err)
+}
+
+// first returns the first error of the slice that is a failure, if any.
+func (b *Breaker) first(errs []error) error {
+	for _, err := range errs {
+		if b.failure(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Breaker) time() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *Breaker) set(s State, now time.Time) {
+	from := b.state
+	b.state, b.since = s, now
+	b.calls, b.failed, b.slow, b.trials, b.trialed = 0, 0, 0, 0, 0
+	if from != s && b.StateChanges != nil {
+		b.StateChanges(from, s)
+	}
+}
+
+// update moves an expired Open Breaker to HalfOpen and starts a new Window in
+// a Closed Breaker.
+func (b *Breaker) update(now time.Time) {
+	switch b.state {
+	case Closed:
+		window := b.Window
+		if window <= 0 {
+			window = DefaultWindow
+		}
+		if now.Sub(b.since) >= window {
+			b.set(Closed, now)
+		}
+	case Open:
+		d := b.OpenDuration
+		if d <= 0 {
+			d = DefaultOpenDuration
+		}
+		if now.Sub(b.since) >= d {
+			b.set(HalfOpen, now)
+		}
+	}
+}
+
+// State returns the current State of the Breaker.
+func (b *Breaker) State() State {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.update(b.time())
+	return b.state
+}
+
+// Allow returns an OpenCircuitError if the call must be rejected. Otherwise
+// the outcome of the call must be reported to the returned function.
+func (b *Breaker) Allow() (func(error), error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	now := b.time()
+	b.update(now)
+	switch b.state {
+	case Open:
+		return nil, OpenCircuitError{Open}
+	case HalfOpen:
+		n := b.HalfOpenCalls
+		if n <= 0 {
+			n = DefaultHalfOpenCalls
+		}
+		if b.trials >= n {
+			return nil, OpenCircuitError{HalfOpen}
+		}
+		b.trials++
+	}
+	state, since := b.state, b.since
+	return func(err error) {
+		b.done(state, since, now, err)
+	}, nil
+}
+
+func (b *Breaker) done(state State, since, start time.Time, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	now := b.time()
+	if b.state != state || !b.since.Equal(since) {
+		// The call started in a previous State or Window.
+		return
+	}
+	failed := b.failure(err)
+	slow := !failed && b.SlowCall > 0 && now.Sub(start) > b.SlowCall
+	if state == HalfOpen {
+		if failed || slow {
+			b.set(Open, now)
+			return
+		}
+		n := b.HalfOpenCalls
+		if n <= 0 {
+			n = DefaultHalfOpenCalls
+		}
+		if b.trialed++; b.trialed >= n {
+			b.set(Closed, now)
+		}
+		return
+	}
+	b.calls++
+	if failed {
+		b.failed++
+	}
+	if slow {
+		b.slow++
+	}
+	min := b.MinCalls
+	if min <= 0 {
+		min = DefaultMinCalls
+	}
+	if b.calls < min {
+		return
+	}
+	errorRate := b.ErrorRate
+	if errorRate <= 0 {
+		errorRate = DefaultErrorRate
+	}
+	slowCallRate := b.SlowCallRate
+	if slowCallRate <= 0 {
+		slowCallRate = DefaultSlowCallRate
+	}
+	if float64(b.failed) >= errorRate*float64(b.calls) || float64(b.slow) >= slowCallRate*float64(b.calls) {
+		b.set(Open, now)
+	}
+}
@@ -0,0 +1,149 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestingBreaker() (*Breaker, *time.Time, *[]string) {
+	now := time.Unix(1000, 0)
+	changes := []string(nil)
+	b := &Breaker{
+		MinCalls:     4,
+		SlowCall:     time.Second,
+		now:          func() time.Time { return now },
+		StateChanges: func(from, to State) { changes = append(changes, fmt.Sprint(from, ">", to)) },
+	}
+	return b, &now, &changes
+}
+
+func call(b *Breaker, now *time.Time, d time.Duration, err error) error {
+	done, berr := b.Allow()
+	if berr != nil {
+		return berr
+	}
+	*now = now.Add(d)
+	done(err)
+	return nil
+}
+
+func TestBreaker_ErrorRate(t *testing.T) {
+	b, now, changes := TestingBreaker()
+	problem := errors.New("problem")
+	for _, err := range []error{nil, problem, nil} {
+		if err := call(b, now, 0, err); err != nil {
+			t.Error(err)
+		}
+	}
+	if s := b.State(); s != Closed {
+		t.Error(s)
+	}
+	if err := call(b, now, 0, problem); err != nil {
+		t.Error(err)
+	}
+	if s := b.State(); s != Open {
+		t.Error(s)
+	}
+	if err := call(b, now, 0, nil); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Open Circuit: Open` {
+		t.Error(s)
+	}
+	if s := fmt.Sprint(*changes); s != `[Closed>Open]` {
+		t.Error(s)
+	}
+}
+
+func TestBreaker_SlowCallRate(t *testing.T) {
+	b, now, _ := TestingBreaker()
+	for _, d := range []time.Duration{0, 2 * time.Second, 0, 2 * time.Second} {
+		if err := call(b, now, d, nil); err != nil {
+			t.Error(err)
+		}
+	}
+	if s := b.State(); s != Open {
+		t.Error(s)
+	}
+}
+
+func TestBreaker_Window(t *testing.T) {
+	b, now, _ := TestingBreaker()
+	problem := errors.New("problem")
+	for i := 0; i < 3; i++ {
+		if err := call(b, now, 0, problem); err != nil {
+			t.Error(err)
+		}
+	}
+	// The failures of the previous Window are forgotten.
+	*now = now.Add(DefaultWindow)
+	for _, err := range []error{problem, nil, nil, nil} {
+		if err := call(b, now, 0, err); err != nil {
+			t.Error(err)
+		}
+	}
+	if s := b.State(); s != Closed {
+		t.Error(s)
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	b, now, changes := TestingBreaker()
+	problem := errors.New("problem")
+	for i := 0; i < 4; i++ {
+		call(b, now, 0, problem)
+	}
+	*now = now.Add(DefaultOpenDuration)
+	if s := b.State(); s != HalfOpen {
+		t.Error(s)
+	}
+	// Only one trial call at a time.
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Open Circuit: HalfOpen` {
+		t.Error(s)
+	}
+	done(problem)
+	if s := b.State(); s != Open {
+		t.Error(s)
+	}
+	*now = now.Add(DefaultOpenDuration)
+	if err := call(b, now, 0, nil); err != nil {
+		t.Error(err)
+	}
+	if s := b.State(); s != Closed {
+		t.Error(s)
+	}
+	if s := fmt.Sprint(*changes); s != `[Closed>Open Open>HalfOpen HalfOpen>Open Open>HalfOpen HalfOpen>Closed]` {
+		t.Error(s)
+	}
+}
+
+func TestBreaker_Failure(t *testing.T) {
+	b, now, _ := TestingBreaker()
+	b.Failure = BackendFailure
+	for _, err := range []error{r.ErrNotFound, fmt.Errorf("wrapped: %w", r.ErrDuplicateKey), r.ErrNotFound, r.ErrNotFound} {
+		if err := call(b, now, 0, err); err != nil {
+			t.Error(err)
+		}
+	}
+	if s := b.State(); s != Closed {
+		t.Error(s)
+	}
+	for i := 0; i < 4; i++ {
+		call(b, now, 0, r.ErrConnectionLost)
+	}
+	if s := b.State(); s != Open {
+		t.Error(s)
+	}
+	if BackendFailure(r.TableValidationError{Reason: r.ErrEmptyTableName}) {
+		t.Error("TableValidationError")
+	}
+}
@@ -0,0 +1,17 @@
+/*
+Circuit breaker for CacheProviders and RowProviders.
+
+A Breaker is Closed while the calls succeed. When the rate of failed or slow
+calls in a Window exceeds its thresholds, it becomes Open and rejects every
+call with an OpenCircuitError without waiting for the failing backend. After
+OpenDuration it becomes HalfOpen and lets a few trial calls through: if they
+succeed it becomes Closed again, otherwise Open. Failure decides which errors
+count as failed calls, like BackendFailure, which ignores the errors caused by
+the accesses themselves.
+
+Cache and Row wrap any CacheProvider and RowProvider with a Breaker. A Cache
+with an Open Breaker, combined with the CacheFallback or CacheIgnore
+CachePolicy, makes Heptane skip the failing cache entirely, while a Row with an
+Open Breaker fails fast.
+*/
+package heptane
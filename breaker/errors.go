@@ -0,0 +1,11 @@
+package heptane
+
+// OpenCircuitError is returned when a call is rejected by an Open or HalfOpen
+// Breaker.
+type OpenCircuitError struct {
+	State State
+}
+
+func (e OpenCircuitError) Error() string {
+	return "Open Circuit: " + e.State.String()
+}
@@ -0,0 +1,103 @@
+package heptane
+
+import (
+	c "github.com/heptanes/heptane/cache"
+	r "github.com/heptanes/heptane/row"
+)
+
+// Cache implements CacheProvider, forwarding the CacheAccesses to
+// CacheProvider through Breaker.
+type Cache struct {
+	c.CacheProvider
+	*Breaker
+}
+
+// Access implements CacheProvider.
+func (p Cache) Access(a c.CacheAccess) error {
+	done, err := p.Breaker.Allow()
+	if err != nil {
+		return err
+	}
+	err = p.CacheProvider.Access(a)
+	done(err)
+	return err
+}
+
+// AccessSlice implements CacheProvider. The CacheAccesses are a single call of
+// the Breaker, which fails if any of them fails. An empty slice is not a call.
+func (p Cache) AccessSlice(aa []c.CacheAccess) []error {
+	if len(aa) == 0 {
+		return p.CacheProvider.AccessSlice(aa)
+	}
+	done, err := p.Breaker.Allow()
+	if err != nil {
+		return repeat(err, len(aa))
+	}
+	errs := p.CacheProvider.AccessSlice(aa)
+	done(p.Breaker.first(errs))
+	return errs
+}
+
+// Invalidate implements CacheInvalidator if CacheProvider does.
+func (p Cache) Invalidate(keys ...c.CacheKey) {
+	if ci, ok := p.CacheProvider.(c.CacheInvalidator); ok {
+		ci.Invalidate(keys...)
+	}
+}
+
+// Row implements RowProvider, forwarding the RowAccesses to RowProvider
+// through Breaker.
+type Row struct {
+	r.RowProvider
+	*Breaker
+}
+
+// Access implements RowProvider.
+func (p Row) Access(a r.RowAccess) error {
+	done, err := p.Breaker.Allow()
+	if err != nil {
+		return err
+	}
+	err = p.RowProvider.Access(a)
+	done(err)
+	return err
+}
+
+// AccessSlice implements RowProvider. The RowAccesses are a single call of
+// the Breaker, which fails if any of them fails. An empty slice is not a call.
+func (p Row) AccessSlice(aa []r.RowAccess) []error {
+	if len(aa) == 0 {
+		return p.RowProvider.AccessSlice(aa)
+	}
+	done, err := p.Breaker.Allow()
+	if err != nil {
+		return repeat(err, len(aa))
+	}
+	errs := p.RowProvider.AccessSlice(aa)
+	done(p.Breaker.first(errs))
+	return errs
+}
+
+// VerifyTable implements TableVerifier if RowProvider does. It does not go
+// through Breaker.
+func (p Row) VerifyTable(t r.Table) error {
+	if tv, ok := p.RowProvider.(r.TableVerifier); ok {
+		return tv.VerifyTable(t)
+	}
+	return nil
+}
+
+// InvalidateTable implements TableInvalidator if RowProvider does.
+func (p Row) InvalidateTable(tn r.TableName) {
+	if ti, ok := p.RowProvider.(r.TableInvalidator); ok {
+		ti.InvalidateTable(tn)
+	}
+}
+
+func repeat(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
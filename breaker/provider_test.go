@@ -0,0 +1,75 @@
+package heptane
+
+import (
+	"errors"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/mock"
+	r "github.com/heptanes/heptane/row"
+	rm "github.com/heptanes/heptane/row/mock"
+)
+
+func TestCache(t *testing.T) {
+	m := &cm.Cache{}
+	p := Cache{m, &Breaker{MinCalls: 1}}
+	m.Mock(c.CacheSet{Key: "foo"}, errors.New("problem"))
+	if err := p.Access(c.CacheSet{Key: "foo"}); err == nil || err.Error() != "problem" {
+		t.Error(err)
+	}
+	// The CacheProvider is not called anymore.
+	for _, err := range p.AccessSlice([]c.CacheAccess{c.CacheSet{Key: "foo"}, c.CacheSet{Key: "bar"}}) {
+		if _, ok := err.(OpenCircuitError); !ok {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRow(t *testing.T) {
+	m := &rm.Row{}
+	p := Row{m, &Breaker{MinCalls: 1}}
+	tb := r.Table{Name: "table1"}
+	m.Mock(r.RowDelete{Table: tb}, nil)
+	if errs := p.AccessSlice([]r.RowAccess{r.RowDelete{Table: tb}}); errs[0] != nil {
+		t.Error(errs[0])
+	}
+	tb2 := r.Table{Name: "table2"}
+	m.Mock(r.RowDelete{Table: tb2}, errors.New("problem"))
+	if err := p.Access(r.RowDelete{Table: tb2}); err == nil || err.Error() != "problem" {
+		t.Error(err)
+	}
+	if s := p.State(); s != Open {
+		t.Error(s)
+	}
+	if err := p.Access(r.RowDelete{Table: tb}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(OpenCircuitError); !ok {
+		t.Error(err)
+	}
+}
+
+func TestRow_Empty(t *testing.T) {
+	m := &rm.Row{}
+	b := &Breaker{MinCalls: 1}
+	p := Row{m, b}
+	tb, tb2 := r.Table{Name: "table1"}, r.Table{Name: "table2"}
+	m.Mock(r.RowDelete{Table: tb}, nil)
+	m.Mock(r.RowDelete{Table: tb2}, errors.New("problem"))
+	p.Access(r.RowDelete{Table: tb2})
+	b.m.Lock()
+	b.since = b.since.Add(-DefaultOpenDuration)
+	b.m.Unlock()
+	// The empty slice does not use the trial call of the HalfOpen Breaker.
+	if errs := p.AccessSlice(nil); len(errs) != 0 {
+		t.Error(errs)
+	}
+	if s := b.State(); s != HalfOpen {
+		t.Error(s)
+	}
+	if err := p.Access(r.RowDelete{Table: tb}); err != nil {
+		t.Error(err)
+	}
+	if s := b.State(); s != Closed {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,9 @@
+/*
+Interface of the buses delivering cache invalidations among instances.
+
+Each instance of an application may keep local copies of the cache entries, so
+a write performed by one instance leaves stale copies in the others. Heptane
+publishes an Invalidation through a Bus after each Create, Update and Delete,
+and the subscribers of the other instances evict the affected CacheKeys.
+*/
+package heptane
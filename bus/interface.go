@@ -0,0 +1,28 @@
+package heptane
+
+import (
+	c "github.com/heptanes/heptane/cache"
+	r "github.com/heptanes/heptane/row"
+)
+
+// Invalidation specifies a cache entry that has been written.
+type Invalidation struct {
+	// Origin identifies the publisher, so it may ignore its own
+	// Invalidations.
+	Origin string
+	// TableName is the name of the table of the written row.
+	TableName r.TableName
+	// Key is the encoded PrimaryKey of the written row.
+	Key c.CacheKey
+}
+
+// Bus is the interface of all implementations that deliver Invalidations
+// among instances.
+type Bus interface {
+	// Publish delivers the Invalidation to the subscribers of every
+	// instance.
+	Publish(Invalidation) error
+	// Subscribe registers a function called with each delivered
+	// Invalidation, and returns the function that unregisters it.
+	Subscribe(func(Invalidation)) func()
+}
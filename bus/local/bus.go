@@ -0,0 +1,45 @@
+package heptane
+
+import (
+	"sync"
+
+	b "github.com/heptanes/heptane/bus"
+)
+
+// Bus implements Bus. The zero value has no subscribers.
+type Bus struct {
+	m           sync.RWMutex
+	subscribers map[int]func(b.Invalidation)
+	next        int
+}
+
+// Publish implements Bus. The subscribers are called before it returns.
+func (p *Bus) Publish(i b.Invalidation) error {
+	p.m.RLock()
+	subscribers := make([]func(b.Invalidation), 0, len(p.subscribers))
+	for _, f := range p.subscribers {
+		subscribers = append(subscribers, f)
+	}
+	p.m.RUnlock()
+	for _, f := range subscribers {
+		f(i)
+	}
+	return nil
+}
+
+// Subscribe implements Bus.
+func (p *Bus) Subscribe(f func(b.Invalidation)) func() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.subscribers == nil {
+		p.subscribers = map[int]func(b.Invalidation){}
+	}
+	id := p.next
+	p.next++
+	p.subscribers[id] = f
+	return func() {
+		p.m.Lock()
+		defer p.m.Unlock()
+		delete(p.subscribers, id)
+	}
+}
@@ -0,0 +1,27 @@
+package heptane
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	b "github.com/heptanes/heptane/bus"
+)
+
+func TestBus(t *testing.T) {
+	p := &Bus{}
+	received := []string(nil)
+	unsubscribe1 := p.Subscribe(func(i b.Invalidation) { received = append(received, fmt.Sprint(1, i)) })
+	p.Subscribe(func(i b.Invalidation) { received = append(received, fmt.Sprint(2, i)) })
+	if err := p.Publish(b.Invalidation{Origin: "o", TableName: "table1", Key: "k1"}); err != nil {
+		t.Error(err)
+	}
+	unsubscribe1()
+	if err := p.Publish(b.Invalidation{Origin: "o", TableName: "table1", Key: "k2"}); err != nil {
+		t.Error(err)
+	}
+	sort.Strings(received)
+	if s := fmt.Sprint(received); s != `[1 {o table1 k1} 2 {o table1 k1} 2 {o table1 k2}]` {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,7 @@
+/*
+In-process implementation of Bus.
+
+Bus delivers the Invalidations synchronously to the subscribers of the same
+process, for instance several instances of Heptane sharing a CacheProvider.
+*/
+package heptane
@@ -0,0 +1,146 @@
+package heptane
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+
+	b "github.com/heptanes/heptane/bus"
+)
+
+// MaxDatagramSize is the maximum size of the datagrams received.
+const MaxDatagramSize = 65507
+
+// Bus implements Bus.
+type Bus struct {
+	// Address is the host:port where the Invalidations are received. An
+	// empty Address means any free port.
+	Address string
+	// Peers are the host:port of the other instances.
+	Peers []string
+
+	m           sync.RWMutex
+	conn        *net.UDPConn
+	subscribers map[int]func(b.Invalidation)
+	next        int
+}
+
+// Open starts receiving Invalidations. It is called by Publish and Subscribe
+// if needed, but Subscribe ignores its error.
+func (p *Bus) Open() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.conn != nil {
+		return nil
+	}
+	address := p.Address
+	if address == "" {
+		address = ":0"
+	}
+	a, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", a)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	go p.receive(conn)
+	return nil
+}
+
+// LocalAddress returns the host:port where the Invalidations are received, or
+// an empty string if the Bus is not open.
+func (p *Bus) LocalAddress() string {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if p.conn == nil {
+		return ""
+	}
+	return p.conn.LocalAddr().String()
+}
+
+// Close stops receiving Invalidations.
+func (p *Bus) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+func (p *Bus) receive(conn *net.UDPConn) {
+	buf := make([]byte, MaxDatagramSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// The connection was closed.
+			return
+		}
+		i := b.Invalidation{}
+		if err := json.Unmarshal(buf[:n], &i); err != nil {
+			continue
+		}
+		p.m.RLock()
+		subscribers := make([]func(b.Invalidation), 0, len(p.subscribers))
+		for _, f := range p.subscribers {
+			subscribers = append(subscribers, f)
+		}
+		p.m.RUnlock()
+		for _, f := range subscribers {
+			f(i)
+		}
+	}
+}
+
+// Publish implements Bus. The Invalidation is sent to every peer, the first
+// error is returned.
+func (p *Bus) Publish(i b.Invalidation) error {
+	if err := p.Open(); err != nil {
+		return err
+	}
+	q, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	p.m.RLock()
+	conn := p.conn
+	p.m.RUnlock()
+	if conn == nil {
+		return net.ErrClosed
+	}
+	first := error(nil)
+	for _, peer := range p.Peers {
+		a, err := net.ResolveUDPAddr("udp", peer)
+		if err == nil {
+			_, err = conn.WriteToUDP(q, a)
+		}
+		if err != nil && first == nil {
+			first = PublishError{peer, err}
+		}
+	}
+	return first
+}
+
+// Subscribe implements Bus. The subscribers are called from the goroutine
+// receiving the datagrams.
+func (p *Bus) Subscribe(f func(b.Invalidation)) func() {
+	p.Open()
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.subscribers == nil {
+		p.subscribers = map[int]func(b.Invalidation){}
+	}
+	id := p.next
+	p.next++
+	p.subscribers[id] = f
+	return func() {
+		p.m.Lock()
+		defer p.m.Unlock()
+		delete(p.subscribers, id)
+	}
+}
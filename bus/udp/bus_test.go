@@ -0,0 +1,101 @@
+package heptane
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	b "github.com/heptanes/heptane/bus"
+)
+
+func TestingBuses(t *testing.T) (*Bus, *Bus) {
+	p1 := &Bus{Address: "127.0.0.1:0"}
+	p2 := &Bus{Address: "127.0.0.1:0"}
+	for _, p := range []*Bus{p1, p2} {
+		if err := p.Open(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { p.Close() })
+	}
+	p1.Peers = []string{p2.LocalAddress()}
+	p2.Peers = []string{p1.LocalAddress()}
+	return p1, p2
+}
+
+func receive(t *testing.T, ch chan b.Invalidation) string {
+	select {
+	case i := <-ch:
+		return fmt.Sprint(i)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	return ""
+}
+
+func TestBus(t *testing.T) {
+	p1, p2 := TestingBuses(t)
+	ch1 := make(chan b.Invalidation, 10)
+	ch2 := make(chan b.Invalidation, 10)
+	p1.Subscribe(func(i b.Invalidation) { ch1 <- i })
+	unsubscribe := p2.Subscribe(func(i b.Invalidation) { ch2 <- i })
+	if err := p1.Publish(b.Invalidation{Origin: "o1", TableName: "table1", Key: "k1"}); err != nil {
+		t.Error(err)
+	}
+	if s := receive(t, ch2); s != `{o1 table1 k1}` {
+		t.Error(s)
+	}
+	if err := p2.Publish(b.Invalidation{Origin: "o2", TableName: "table1", Key: "k2"}); err != nil {
+		t.Error(err)
+	}
+	if s := receive(t, ch1); s != `{o2 table1 k2}` {
+		t.Error(s)
+	}
+	unsubscribe()
+	if err := p1.Publish(b.Invalidation{Origin: "o1", TableName: "table1", Key: "k3"}); err != nil {
+		t.Error(err)
+	}
+	// A second subscriber receives the following Invalidation, the first one
+	// does not.
+	ch3 := make(chan b.Invalidation, 10)
+	p2.Subscribe(func(i b.Invalidation) { ch3 <- i })
+	if err := p1.Publish(b.Invalidation{Origin: "o1", TableName: "table1", Key: "k4"}); err != nil {
+		t.Error(err)
+	}
+	for s := receive(t, ch3); s != `{o1 table1 k4}`; s = receive(t, ch3) {
+		if s != `{o1 table1 k3}` {
+			t.Error(s)
+		}
+	}
+	if len(ch2) != 0 {
+		t.Error(len(ch2))
+	}
+}
+
+func TestBus_Malformed(t *testing.T) {
+	p1, p2 := TestingBuses(t)
+	ch := make(chan b.Invalidation, 10)
+	p2.Subscribe(func(i b.Invalidation) { ch <- i })
+	p1.m.RLock()
+	conn := p1.conn
+	p1.m.RUnlock()
+	if _, err := conn.WriteToUDP([]byte("foo"), p2.conn.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Error(err)
+	}
+	if err := p1.Publish(b.Invalidation{TableName: "table1", Key: "k1"}); err != nil {
+		t.Error(err)
+	}
+	if s := receive(t, ch); s != `{ table1 k1}` {
+		t.Error(s)
+	}
+}
+
+func TestBus_PublishError(t *testing.T) {
+	p := &Bus{Address: "127.0.0.1:0", Peers: []string{"foo:bar"}}
+	defer p.Close()
+	if err := p.Publish(b.Invalidation{TableName: "table1", Key: "k1"}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(PublishError); !ok {
+		t.Error(err)
+	}
+}
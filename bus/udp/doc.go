@@ -0,0 +1,9 @@
+/*
+Implementation of Bus relying on UDP.
+
+Bus sends each Invalidation as a JSON datagram to the other instances, the
+Peers, and delivers the datagrams it receives to its subscribers. Delivery is
+best effort: lost datagrams leave stale copies until they expire or are
+written again.
+*/
+package heptane
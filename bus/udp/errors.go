@@ -0,0 +1,17 @@
+package heptane
+
+import "fmt"
+
+// PublishError is produced when an Invalidation cannot be sent to a peer.
+type PublishError struct {
+	Peer string
+	Err  error
+}
+
+func (e PublishError) Error() string {
+	return fmt.Sprintf("Publish Error to Peer %v: %v", e.Peer, e.Err)
+}
+
+func (e PublishError) Unwrap() error {
+	return e.Err
+}
@@ -6,5 +6,8 @@ operations Set and Get. Values are stored without expiration time.
 
 CacheGet must be passed as reference so the Value set by the CacheProvider may
 be read by the client code.
+
+CacheProviders keeping local copies of the entries implement CacheInvalidator,
+so the copies may be evicted when other instances write the entries.
 */
 package heptane
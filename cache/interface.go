@@ -35,3 +35,11 @@ type CacheProvider interface {
 	// AccessSlice performs several acccesses to the cache.
 	AccessSlice([]CacheAccess) []error
 }
+
+// CacheInvalidator is the interface of the CacheProviders that keep local
+// copies of the cache entries, which may be evicted when another instance
+// writes them.
+type CacheInvalidator interface {
+	// Invalidate evicts the local copies of the given cache entries.
+	Invalidate(...CacheKey)
+}
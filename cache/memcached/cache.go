@@ -0,0 +1,143 @@
+package heptane
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	rg "github.com/heptanes/heptane/ring"
+)
+
+// MaxKeyLength is the maximum length of a memcached key.
+const MaxKeyLength = 250
+
+// Cache implements CacheProvider.
+type Cache struct {
+	// Servers are the host:port of the servers. They are also the names of
+	// the Nodes of the Ring, so the order of the Servers does not matter.
+	Servers []string
+	// MaxIdle is the maximum number of idle connections kept open for each
+	// server. Values lower than 1 mean 1.
+	MaxIdle int
+	// Timeout, if not zero, is the maximum duration of each round trip.
+	Timeout time.Duration
+	// Dial, if not nil, opens the connections instead of net.Dial.
+	Dial func(address string) (net.Conn, error)
+
+	once  sync.Once
+	ring  *rg.Ring
+	pools []*pool
+}
+
+func (p *Cache) init() {
+	p.once.Do(func() {
+		nodes := []rg.Node(nil)
+		for _, s := range p.Servers {
+			nodes = append(nodes, rg.Node{Name: s})
+			p.pools = append(p.pools, &pool{address: s, cache: p})
+		}
+		p.ring = rg.New(nodes, 0)
+	})
+}
+
+// Key returns the memcached key of a CacheKey: the CacheKey itself, or its
+// SHA-256 hash if it is empty, longer than MaxKeyLength or contains spaces or
+// control characters.
+func Key(k c.CacheKey) string {
+	valid := len(k) <= MaxKeyLength
+	for i := 0; valid && i < len(k); i++ {
+		valid = k[i] > ' ' && k[i] != 0x7f
+	}
+	if valid && len(k) > 0 {
+		return string(k)
+	}
+	d := sha256.Sum256([]byte(k))
+	return "sha256:" + hex.EncodeToString(d[:])
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The commands of each server are sent in
+// a single round trip and the servers are accessed in parallel. Consecutive
+// CacheGets of the same server are merged into a single get.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	p.init()
+	errs := make([]error, len(aa))
+	cmds := make([]*command, len(aa))
+	keys := make([]string, len(aa))
+	servers := make([][]*command, len(p.pools))
+	for i, a := range aa {
+		var k c.CacheKey
+		var v c.CacheValue
+		name := ""
+		switch a := a.(type) {
+		case *c.CacheGet:
+			k, name = a.Key, "get"
+		case c.CacheSet:
+			k, v, name = a.Key, a.Value, "set"
+		case *c.CacheSet:
+			k, v, name = a.Key, a.Value, "set"
+		default:
+			errs[i] = UnsupportedCacheAccessTypeError{a}
+			continue
+		}
+		if len(p.pools) == 0 {
+			errs[i] = NoServerError{}
+			continue
+		}
+		if name == "set" && v == nil {
+			name = "delete"
+		}
+		keys[i] = Key(k)
+		s := p.ring.Node([]byte(keys[i]))
+		if n := len(servers[s]); name == "get" && n > 0 && servers[s][n-1].name == "get" {
+			cmds[i] = servers[s][n-1]
+			cmds[i].keys = append(cmds[i].keys, keys[i])
+			continue
+		}
+		cmds[i] = &command{name: name, keys: []string{keys[i]}, value: v}
+		servers[s] = append(servers[s], cmds[i])
+	}
+	wg := sync.WaitGroup{}
+	for s, cmds := range servers {
+		if cmds == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(s int, cmds []*command) {
+			defer wg.Done()
+			p.pools[s].do(cmds)
+		}(s, cmds)
+	}
+	wg.Wait()
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		if errs[i] = cmd.err; errs[i] != nil {
+			continue
+		}
+		if a, ok := aa[i].(*c.CacheGet); ok {
+			a.Value = nil
+			if v, ok := cmd.values[keys[i]]; ok {
+				// An empty value is a hit.
+				a.Value = append(c.CacheValue{}, v...)
+			}
+		}
+	}
+	return errs
+}
+
+// Close closes the idle connections to every server.
+func (p *Cache) Close() {
+	p.init()
+	for _, pool := range p.pools {
+		pool.close()
+	}
+}
@@ -0,0 +1,203 @@
+package heptane
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+func TestKey(t *testing.T) {
+	long := c.CacheKey(strings.Repeat("x", MaxKeyLength+1))
+	for k, expected := range map[c.CacheKey]string{
+		"foo":    "foo",
+		long[1:]: string(long[1:]),
+		long:     "sha256:90d738c31c5ee1241cbcd2ff3d4aa1257ba5b7d717c545c397d37dc060ecf7ff",
+		"a b":    "sha256:c8687a08aa5d6ed2044328fa6a697ab8e96dc34291e8c2034ae8c38e6fcc6d65",
+		"a\nb":   "sha256:7e18f737311b2dc3b2f269dd78396b0351f14fb66efa879f768cb23181883c78",
+		"":       "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	} {
+		if s := Key(k); s != expected {
+			t.Errorf("%q %v", k, s)
+		}
+	}
+}
+
+func TestAccess(t *testing.T) {
+	s := NewTestingServer(t)
+	p := &Cache{Servers: []string{s.Address()}}
+	defer p.Close()
+	get := &c.CacheGet{Key: "foo"}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value != nil {
+		t.Error(get.Value)
+	}
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if string(get.Value) != "bar" {
+		t.Error(get.Value)
+	}
+	if err := p.Access(&c.CacheSet{Key: "foo", Value: c.CacheValue{}}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value == nil || len(get.Value) != 0 {
+		t.Error(get.Value)
+	}
+	if err := p.Access(c.CacheSet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value != nil {
+		t.Error(get.Value)
+	}
+	if s := fmt.Sprintf("%q", s.Commands()); s != `["get foo" "set foo 0 0 3" "get foo" "set foo 0 0 0" "get foo" "delete foo" "get foo"]` {
+		t.Error(s)
+	}
+	if s.Connections() != 1 {
+		t.Error(s.Connections())
+	}
+}
+
+func TestAccess_Unsupported(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported CacheAccess Type: heptane.CacheGet{Key:"", Value:heptane.CacheValue(nil)}` {
+		t.Error(s)
+	}
+	if err := p.Access(&c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `No Server in Cache` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice_MultiGet(t *testing.T) {
+	s := NewTestingServer(t)
+	p := &Cache{Servers: []string{s.Address()}}
+	defer p.Close()
+	long := c.CacheKey(strings.Repeat("x", 300))
+	gets := []*c.CacheGet{{Key: "foo"}, {Key: "bar"}, {Key: long}, {Key: "foo"}}
+	errs := p.AccessSlice([]c.CacheAccess{
+		c.CacheSet{Key: "foo", Value: c.CacheValue("1")},
+		c.CacheSet{Key: long, Value: c.CacheValue("2")},
+		gets[0], gets[1], gets[2],
+		c.CacheSet{Key: "foo"},
+		gets[3],
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if s := fmt.Sprintf("%q", []string{string(gets[0].Value), string(gets[1].Value), string(gets[2].Value), string(gets[3].Value)}); s != `["1" "" "2" ""]` {
+		t.Error(s)
+	}
+	if gets[1].Value != nil || gets[3].Value != nil {
+		t.Error(gets[1].Value, gets[3].Value)
+	}
+	if s := fmt.Sprintf("%q", s.Commands()); s != fmt.Sprintf(`["set foo 0 0 1" "set %[1]v 0 0 1" "get foo bar %[1]v" "delete foo" "get foo"]`, Key(long)) {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice_Servers(t *testing.T) {
+	servers := []*TestingServer{NewTestingServer(t), NewTestingServer(t), NewTestingServer(t)}
+	p := &Cache{Servers: []string{servers[0].Address(), servers[1].Address(), servers[2].Address()}}
+	defer p.Close()
+	sets := []c.CacheAccess(nil)
+	gets := []c.CacheAccess(nil)
+	for i := 0; i < 30; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		sets = append(sets, c.CacheSet{Key: k, Value: c.CacheValue(k)})
+		gets = append(gets, &c.CacheGet{Key: k})
+	}
+	for _, aa := range [][]c.CacheAccess{sets, gets} {
+		for _, err := range p.AccessSlice(aa) {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	for _, a := range gets {
+		a := a.(*c.CacheGet)
+		if string(a.Value) != string(a.Key) {
+			t.Error(a.Key, a.Value)
+		}
+	}
+	stored := 0
+	for i, s := range servers {
+		commands := s.Commands()
+		gets := 0
+		for _, command := range commands {
+			if strings.HasPrefix(command, "get ") {
+				gets++
+			}
+		}
+		if gets != 1 {
+			t.Error(i, commands)
+		}
+		for _, a := range sets {
+			if _, ok := s.Get(string(a.(c.CacheSet).Key)); ok {
+				stored++
+			}
+		}
+		if s.Connections() != 1 {
+			t.Error(i, s.Connections())
+		}
+	}
+	if stored != 30 {
+		t.Error(stored)
+	}
+}
+
+func TestAccessSlice_ServerError(t *testing.T) {
+	s := NewTestingServer(t)
+	p := &Cache{Servers: []string{s.Address()}}
+	defer p.Close()
+	p.init()
+	cmds := []*command{{name: "fail", keys: []string{"foo"}}, {name: "set", keys: []string{"foo"}, value: []byte("bar")}}
+	p.pools[0].do(cmds)
+	if s := fmt.Sprint(cmds[0].err, cmds[1].err); s != `Memcached Error: SERVER_ERROR out of memory <nil>` {
+		t.Error(s)
+	}
+	// An ERROR reply closes the connection.
+	cmds = []*command{{name: "unknown", keys: []string{"foo"}}, {name: "get", keys: []string{"foo"}}}
+	p.pools[0].do(cmds)
+	if s := fmt.Sprint(cmds[0].err, cmds[1].err); s != `Memcached Error: ERROR Memcached Error: ERROR` {
+		t.Error(s)
+	}
+	get := &c.CacheGet{Key: "foo"}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if string(get.Value) != "bar" {
+		t.Error(get.Value)
+	}
+	if s.Connections() != 2 {
+		t.Error(s.Connections())
+	}
+}
+
+func TestRead_ProtocolError(t *testing.T) {
+	for name, input := range map[string]string{
+		"set":    "DELETED\r\n",
+		"delete": "STORED\r\n",
+		"get":    "VALUE foo 0\r\n",
+	} {
+		cmd := &command{name: name}
+		if err := cmd.read(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Error(name)
+		} else if _, ok := err.(ProtocolError); !ok {
+			t.Error(name, err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+/*
+Implementation of CacheProvider relying on memcached.
+
+Cache speaks the text protocol with a set of memcached servers, distributing
+the CacheKeys among them with a consistent hashing Ring. Each CacheSet is a set,
+or a delete when its CacheValue is nil. AccessSlice sends the commands of each
+server in a single round trip, accessing the servers in parallel, and merges
+consecutive CacheGets into a single get of several keys.
+
+Empty CacheKeys, CacheKeys longer than 250 bytes and CacheKeys containing spaces
+or control characters are not valid memcached keys, so they are replaced by
+their SHA-256 hash.
+*/
+package heptane
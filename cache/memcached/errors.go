@@ -0,0 +1,43 @@
+package heptane
+
+import (
+	"fmt"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// UnsupportedCacheAccessTypeError is produced when the type of a CacheAccess
+// is not supported. Current supported types are CacheGet and CacheSet.
+type UnsupportedCacheAccessTypeError struct {
+	CacheAccess c.CacheAccess
+}
+
+func (e UnsupportedCacheAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported CacheAccess Type: %#v", e.CacheAccess)
+}
+
+// MemcachedError is produced when the server replies with an error.
+type MemcachedError struct {
+	Message string
+}
+
+func (e MemcachedError) Error() string {
+	return fmt.Sprintf("Memcached Error: %v", e.Message)
+}
+
+// ProtocolError is produced when the server sends a malformed or unexpected
+// reply.
+type ProtocolError struct {
+	Reply string
+}
+
+func (e ProtocolError) Error() string {
+	return fmt.Sprintf("Protocol Error: unexpected reply %q", e.Reply)
+}
+
+// NoServerError is produced when a Cache has no Servers.
+type NoServerError struct{}
+
+func (e NoServerError) Error() string {
+	return "No Server in Cache"
+}
@@ -0,0 +1,210 @@
+package heptane
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// command is a command of the text protocol: a get of several keys, a set or
+// a delete of a key.
+type command struct {
+	name   string
+	keys   []string
+	value  []byte
+	values map[string][]byte
+	done   bool
+	err    error
+}
+
+func (cmd *command) write(w *bufio.Writer) {
+	w.WriteString(cmd.name)
+	for _, k := range cmd.keys {
+		w.WriteByte(' ')
+		w.WriteString(k)
+	}
+	if cmd.name == "set" {
+		w.WriteString(" 0 0 ")
+		w.WriteString(strconv.Itoa(len(cmd.value)))
+		w.WriteString("\r\n")
+		w.Write(cmd.value)
+	}
+	w.WriteString("\r\n")
+}
+
+// readLine reads a line of the text protocol without its terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", ProtocolError{line}
+	}
+	return line[:len(line)-2], nil
+}
+
+// serverError returns the MemcachedError of an error reply, and whether the
+// connection may be out of sync after it.
+func serverError(line string) (error, bool) {
+	switch {
+	case line == "ERROR", strings.HasPrefix(line, "CLIENT_ERROR "):
+		return MemcachedError{line}, true
+	case strings.HasPrefix(line, "SERVER_ERROR "):
+		return MemcachedError{line}, false
+	}
+	return nil, false
+}
+
+// read reads the reply to the command. An error means the connection is no
+// longer usable, but the reply may still have been read.
+func (cmd *command) read(r *bufio.Reader) error {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if err, broken := serverError(line); err != nil {
+			cmd.err = err
+			if broken {
+				return err
+			}
+			return nil
+		}
+		switch cmd.name {
+		case "set":
+			if line != "STORED" {
+				return ProtocolError{line}
+			}
+			return nil
+		case "delete":
+			if line != "DELETED" && line != "NOT_FOUND" {
+				return ProtocolError{line}
+			}
+			return nil
+		}
+		if line == "END" {
+			return nil
+		}
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return ProtocolError{line}
+		}
+		n, err := strconv.Atoi(fields[3])
+		if err != nil || n < 0 {
+			return ProtocolError{line}
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return err
+		}
+		if cmd.values == nil {
+			cmd.values = map[string][]byte{}
+		}
+		cmd.values[fields[1]] = value[:n]
+	}
+}
+
+// conn is a connection to a server.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// do sends several commands in a single round trip and reads their replies.
+// An error means the connection is no longer usable.
+func (c *conn) do(timeout time.Duration, cmds []*command) error {
+	if timeout > 0 {
+		c.SetDeadline(time.Now().Add(timeout))
+	}
+	for _, cmd := range cmds {
+		cmd.write(c.w)
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := cmd.read(c.r); err != nil {
+			return err
+		}
+		cmd.done = true
+	}
+	return nil
+}
+
+// pool keeps the idle connections to a server.
+type pool struct {
+	address string
+	cache   *Cache
+	m       sync.Mutex
+	idle    []*conn
+}
+
+// get returns an idle connection or a new one.
+func (p *pool) get() (*conn, error) {
+	p.m.Lock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.m.Unlock()
+		return c, nil
+	}
+	p.m.Unlock()
+	dial := p.cache.Dial
+	if dial == nil {
+		dial = func(address string) (net.Conn, error) {
+			return net.Dial("tcp", address)
+		}
+	}
+	nc, err := dial(p.address)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{nc, bufio.NewReader(nc), bufio.NewWriter(nc)}, nil
+}
+
+// do sends several commands in a single round trip on a connection of the
+// pool. On a connection error the commands without a reply get the error.
+func (p *pool) do(cmds []*command) {
+	c, err := p.get()
+	if err == nil {
+		err = c.do(p.cache.Timeout, cmds)
+	}
+	if err != nil {
+		for _, cmd := range cmds {
+			if !cmd.done && cmd.err == nil {
+				cmd.err = err
+			}
+		}
+	}
+	if c == nil {
+		return
+	}
+	if err == nil {
+		p.m.Lock()
+		if len(p.idle) < p.cache.MaxIdle || len(p.idle) < 1 {
+			p.idle = append(p.idle, c)
+			c = nil
+		}
+		p.m.Unlock()
+	}
+	if c != nil {
+		c.Close()
+	}
+}
+
+// close closes the idle connections.
+func (p *pool) close() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,116 @@
+package heptane
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestingServer is a fake memcached server supporting get, set and delete.
+type TestingServer struct {
+	m           sync.Mutex
+	listener    net.Listener
+	data        map[string][]byte
+	connections int
+	commands    []string
+}
+
+func NewTestingServer(t *testing.T) *TestingServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &TestingServer{listener: l, data: map[string][]byte{}}
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.m.Lock()
+			s.connections++
+			s.m.Unlock()
+			go s.serve(nc)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *TestingServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *TestingServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		s.m.Lock()
+		s.commands = append(s.commands, line)
+		switch {
+		case len(fields) > 1 && fields[0] == "get":
+			for _, k := range fields[1:] {
+				if v, ok := s.data[k]; ok {
+					fmt.Fprintf(w, "VALUE %s 0 %d\r\n%s\r\n", k, len(v), v)
+				}
+			}
+			w.WriteString("END\r\n")
+		case len(fields) == 5 && fields[0] == "set":
+			n, _ := strconv.Atoi(fields[4])
+			v := make([]byte, n+2)
+			if _, err := io.ReadFull(r, v); err != nil {
+				s.m.Unlock()
+				return
+			}
+			s.data[fields[1]] = v[:n]
+			w.WriteString("STORED\r\n")
+		case len(fields) == 2 && fields[0] == "delete":
+			if _, ok := s.data[fields[1]]; ok {
+				delete(s.data, fields[1])
+				w.WriteString("DELETED\r\n")
+			} else {
+				w.WriteString("NOT_FOUND\r\n")
+			}
+		case len(fields) > 0 && fields[0] == "fail":
+			w.WriteString("SERVER_ERROR out of memory\r\n")
+		default:
+			w.WriteString("ERROR\r\n")
+		}
+		s.m.Unlock()
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *TestingServer) Get(key string) ([]byte, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *TestingServer) Connections() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.connections
+}
+
+func (s *TestingServer) Commands() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string(nil), s.commands...)
+}
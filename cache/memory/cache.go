@@ -0,0 +1,137 @@
+package heptane
+
+import (
+	"container/list"
+	"sync"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// Statistics contains the counters of a Cache.
+type Statistics struct {
+	// Hits is the number of CacheGets that found their entry.
+	Hits uint64
+	// Misses is the number of CacheGets that did not find their entry.
+	Misses uint64
+	// Evictions is the number of entries removed to respect the bounds.
+	Evictions uint64
+	// Entries is the current number of entries.
+	Entries int
+	// Bytes is the current size of the entries.
+	Bytes int
+}
+
+type entry struct {
+	key   c.CacheKey
+	value c.CacheValue
+}
+
+func (e *entry) size() int {
+	return len(e.key) + len(e.value)
+}
+
+// Cache implements CacheProvider and CacheInvalidator. The zero value is an
+// unbounded empty cache.
+type Cache struct {
+	// MaxEntries is the maximum number of entries. Zero means no limit.
+	MaxEntries int
+	// MaxBytes is the maximum size of the entries, the sum of the lengths of
+	// their keys and values. Zero means no limit.
+	MaxBytes int
+
+	m       sync.Mutex
+	lru     list.List
+	entries map[c.CacheKey]*list.Element
+	stats   Statistics
+}
+
+func (p *Cache) Get(a *c.CacheGet) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	e, ok := p.entries[a.Key]
+	if !ok {
+		p.stats.Misses++
+		a.Value = nil
+		return nil
+	}
+	p.stats.Hits++
+	p.lru.MoveToFront(e)
+	a.Value = append(c.CacheValue{}, e.Value.(*entry).value...)
+	return nil
+}
+
+func (p *Cache) Set(a c.CacheSet) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if e, ok := p.entries[a.Key]; ok {
+		p.remove(e)
+	}
+	if a.Value == nil {
+		return nil
+	}
+	n := &entry{a.Key, append(c.CacheValue{}, a.Value...)}
+	if p.MaxBytes > 0 && n.size() > p.MaxBytes {
+		// The entry would evict everything and still not fit.
+		p.stats.Evictions++
+		return nil
+	}
+	if p.entries == nil {
+		p.entries = map[c.CacheKey]*list.Element{}
+	}
+	p.entries[a.Key] = p.lru.PushFront(n)
+	p.stats.Entries++
+	p.stats.Bytes += n.size()
+	for (p.MaxEntries > 0 && p.stats.Entries > p.MaxEntries) ||
+		(p.MaxBytes > 0 && p.stats.Bytes > p.MaxBytes) {
+		p.remove(p.lru.Back())
+		p.stats.Evictions++
+	}
+	return nil
+}
+
+// remove deletes an entry. Called with the lock held.
+func (p *Cache) remove(e *list.Element) {
+	n := p.lru.Remove(e).(*entry)
+	delete(p.entries, n.key)
+	p.stats.Entries--
+	p.stats.Bytes -= n.size()
+}
+
+// Invalidate implements CacheInvalidator.
+func (p *Cache) Invalidate(keys ...c.CacheKey) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, k := range keys {
+		if e, ok := p.entries[k]; ok {
+			p.remove(e)
+		}
+	}
+}
+
+// Statistics returns the current counters of the Cache.
+func (p *Cache) Statistics() Statistics {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.stats
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	switch a := a.(type) {
+	case *c.CacheGet:
+		return p.Get(a)
+	case c.CacheSet:
+		return p.Set(a)
+	case *c.CacheSet:
+		return p.Set(*a)
+	}
+	return UnsupportedCacheAccessTypeError{a}
+}
+
+// AccessSlice implements CacheProvider.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) (errs []error) {
+	for _, a := range aa {
+		errs = append(errs, p.Access(a))
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package heptane
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+func TestCache_Miss(t *testing.T) {
+	p := &Cache{}
+	a := &c.CacheGet{Key: "k", Value: c.CacheValue("stale")}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%#v", a.Value); s != `heptane.CacheValue(nil)` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%+v", p.Statistics()); s != `{Hits:0 Misses:1 Evictions:0 Entries:0 Bytes:0}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_Empty(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheSet{Key: "k", Value: c.CacheValue{}}); err != nil {
+		t.Error(err)
+	}
+	a := &c.CacheGet{Key: "k"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%#v", a.Value); s != `heptane.CacheValue{}` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%+v", p.Statistics()); s != `{Hits:1 Misses:0 Evictions:0 Entries:1 Bytes:1}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_Nil(t *testing.T) {
+	p := &Cache{}
+	for _, v := range []c.CacheValue{c.CacheValue("v"), nil} {
+		if err := p.Access(&c.CacheSet{Key: "k", Value: v}); err != nil {
+			t.Error(err)
+		}
+	}
+	a := &c.CacheGet{Key: "k"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if a.Value != nil {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprintf("%+v", p.Statistics()); s != `{Hits:0 Misses:1 Evictions:0 Entries:0 Bytes:0}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_Copy(t *testing.T) {
+	p := &Cache{}
+	v := c.CacheValue("v")
+	if err := p.Access(c.CacheSet{Key: "k", Value: v}); err != nil {
+		t.Error(err)
+	}
+	v[0] = 'x'
+	a := &c.CacheGet{Key: "k"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := string(a.Value); s != "v" {
+		t.Error(s)
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	p := &Cache{MaxEntries: 2}
+	errs := p.AccessSlice([]c.CacheAccess{
+		c.CacheSet{Key: "a", Value: c.CacheValue("1")},
+		c.CacheSet{Key: "b", Value: c.CacheValue("2")},
+		&c.CacheGet{Key: "a"},
+		c.CacheSet{Key: "c", Value: c.CacheValue("3")},
+	})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	for k, v := range map[c.CacheKey]string{"a": "1", "b": "", "c": "3"} {
+		a := &c.CacheGet{Key: k}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if s := string(a.Value); s != v {
+			t.Error(k, s)
+		}
+	}
+	if s := fmt.Sprintf("%+v", p.Statistics()); s != `{Hits:3 Misses:1 Evictions:1 Entries:2 Bytes:4}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_MaxBytes(t *testing.T) {
+	p := &Cache{MaxBytes: 10}
+	errs := p.AccessSlice([]c.CacheAccess{
+		c.CacheSet{Key: "a", Value: c.CacheValue("1234")},
+		c.CacheSet{Key: "b", Value: c.CacheValue("1234")},
+		c.CacheSet{Key: "c", Value: c.CacheValue("1234")},
+		c.CacheSet{Key: "d", Value: c.CacheValue("12345678901")},
+	})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%+v", p.Statistics()); s != `{Hits:0 Misses:0 Evictions:2 Entries:2 Bytes:10}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	p := &Cache{}
+	p.Access(c.CacheSet{Key: "a", Value: c.CacheValue("1")})
+	p.Access(c.CacheSet{Key: "b", Value: c.CacheValue("2")})
+	p.Invalidate("a", "c")
+	for k, expected := range map[c.CacheKey]string{"a": "", "b": "2"} {
+		a := &c.CacheGet{Key: k}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if string(a.Value) != expected {
+			t.Error(k, a.Value)
+		}
+	}
+	if s := p.Statistics(); s.Entries != 1 || s.Bytes != 2 {
+		t.Error(s)
+	}
+}
+
+func TestCache_Unsupported(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheGet{Key: "k"}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported CacheAccess Type: heptane.CacheGet{Key:"k", Value:heptane.CacheValue(nil)}` {
+		t.Error(s)
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	p := &Cache{MaxEntries: 10}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k := c.CacheKey(fmt.Sprint(j % 20))
+				p.Access(c.CacheSet{Key: k, Value: c.CacheValue(fmt.Sprint(i))})
+				p.Access(&c.CacheGet{Key: k})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := p.Statistics(); s.Entries != 10 || s.Hits+s.Misses != 800 {
+		t.Error(s)
+	}
+}
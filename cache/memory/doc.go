@@ -0,0 +1,9 @@
+/*
+In-memory implementation of CacheProvider.
+
+Cache keeps the entries in memory, bounded by their number and their size, and
+evicts the least recently used entries first. Setting a nil CacheValue removes
+the entry, so the following CacheGet is a miss, while an empty CacheValue is
+stored and returned as a hit.
+*/
+package heptane
@@ -0,0 +1,120 @@
+package heptane
+
+import (
+	"sync"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// Cache implements CacheProvider.
+type Cache struct {
+	// Address is the host:port of the server.
+	Address string
+	// Options contains the connection parameters of the server.
+	Options Options
+
+	once sync.Once
+	pool *pool
+}
+
+func (p *Cache) getPool() *pool {
+	p.once.Do(func() {
+		p.pool = &pool{address: p.Address, options: &p.Options}
+	})
+	return p.pool
+}
+
+// command returns the command performing the given CacheAccess.
+func command(a c.CacheAccess) ([][]byte, error) {
+	switch a := a.(type) {
+	case *c.CacheGet:
+		return [][]byte{[]byte("GET"), []byte(a.Key)}, nil
+	case c.CacheSet:
+		return setCommand(a), nil
+	case *c.CacheSet:
+		return setCommand(*a), nil
+	}
+	return nil, UnsupportedCacheAccessTypeError{a}
+}
+
+func setCommand(a c.CacheSet) [][]byte {
+	if a.Value == nil {
+		return [][]byte{[]byte("DEL"), []byte(a.Key)}
+	}
+	return [][]byte{[]byte("SET"), []byte(a.Key), a.Value}
+}
+
+// result stores the reply to the command of the given CacheAccess.
+func result(a c.CacheAccess, reply interface{}) error {
+	if err, ok := reply.(RedisError); ok {
+		return err
+	}
+	switch a := a.(type) {
+	case *c.CacheGet:
+		v, ok := reply.([]byte)
+		if !ok {
+			return ProtocolError{reply}
+		}
+		if v != nil {
+			// An empty value is a hit.
+			v = append(c.CacheValue{}, v...)
+		}
+		a.Value = v
+		return nil
+	case c.CacheSet:
+		return setResult(a, reply)
+	case *c.CacheSet:
+		return setResult(*a, reply)
+	}
+	return UnsupportedCacheAccessTypeError{a}
+}
+
+func setResult(a c.CacheSet, reply interface{}) error {
+	if a.Value == nil {
+		if _, ok := reply.(int64); !ok {
+			return ProtocolError{reply}
+		}
+	} else if reply != "OK" {
+		return ProtocolError{reply}
+	}
+	return nil
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. All the commands are sent in a single
+// round trip.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	errs := make([]error, len(aa))
+	commands := [][][]byte(nil)
+	indexes := []int(nil)
+	for i, a := range aa {
+		args, err := command(a)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		commands = append(commands, args)
+		indexes = append(indexes, i)
+	}
+	if commands == nil {
+		return errs
+	}
+	replies, err := p.getPool().do(commands)
+	for j, i := range indexes {
+		if err != nil {
+			errs[i] = err
+		} else {
+			errs[i] = result(aa[i], replies[j])
+		}
+	}
+	return errs
+}
+
+// Close closes the idle connections.
+func (p *Cache) Close() {
+	p.getPool().close()
+}
@@ -0,0 +1,221 @@
+package heptane
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+func TestReadReply(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("+OK\r\n-ERR foo\r\n:42\r\n$3\r\nfoo\r\n$0\r\n\r\n$-1\r\n*2\r\n$1\r\na\r\n:1\r\n*-1\r\n"))
+	for _, expected := range []string{
+		`"OK"`,
+		`heptane.RedisError{Message:"ERR foo"}`,
+		`42`,
+		`[]byte{0x66, 0x6f, 0x6f}`,
+		`[]byte{}`,
+		`[]byte(nil)`,
+		`[]interface {}{[]uint8{0x61}, 1}`,
+		`[]interface {}(nil)`,
+	} {
+		if reply, err := readReply(r); err != nil {
+			t.Error(err)
+		} else if s := fmt.Sprintf("%#v", reply); s != expected {
+			t.Error(s)
+		}
+	}
+}
+
+func TestReadReply_ProtocolError(t *testing.T) {
+	for _, input := range []string{"?\r\n", "OK\n", ":x\r\n"} {
+		if _, err := readReply(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Error(input)
+		} else if _, ok := err.(ProtocolError); !ok {
+			t.Error(err)
+		}
+	}
+}
+
+func TestAccess(t *testing.T) {
+	s := NewTestingServer(t, "")
+	p := &Cache{Address: s.Address()}
+	defer p.Close()
+	get := &c.CacheGet{Key: "foo"}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value != nil {
+		t.Error(get.Value)
+	}
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if string(get.Value) != "bar" {
+		t.Error(get.Value)
+	}
+	if err := p.Access(&c.CacheSet{Key: "foo", Value: c.CacheValue{}}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value == nil || len(get.Value) != 0 {
+		t.Error(get.Value)
+	}
+	if err := p.Access(c.CacheSet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(get); err != nil {
+		t.Error(err)
+	} else if get.Value != nil {
+		t.Error(get.Value)
+	}
+	if s := s.CommandsString(); s != `["GET foo" "SET foo bar" "GET foo" "SET foo " "GET foo" "DEL foo" "GET foo"]` {
+		t.Error(s)
+	}
+	if s.Connections() != 1 {
+		t.Error(s.Connections())
+	}
+}
+
+func TestAccess_Unsupported(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported CacheAccess Type: heptane.CacheGet{Key:"", Value:heptane.CacheValue(nil)}` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice_Pipeline(t *testing.T) {
+	s := NewTestingServer(t, "")
+	writes := 0
+	p := &Cache{Address: s.Address(), Options: Options{
+		Dial: func(address string) (net.Conn, error) {
+			nc, err := net.Dial("tcp", address)
+			return countingConn{nc, &writes}, err
+		},
+	}}
+	defer p.Close()
+	get1 := &c.CacheGet{Key: "foo"}
+	get2 := &c.CacheGet{Key: "baz"}
+	errs := p.AccessSlice([]c.CacheAccess{
+		c.CacheSet{Key: "foo", Value: c.CacheValue("bar")},
+		c.CacheGet{Key: "foo"},
+		get1,
+		get2,
+	})
+	if s := fmt.Sprint(errs); !strings.HasPrefix(s, "[<nil> Unsupported CacheAccess Type: ") || !strings.HasSuffix(s, " <nil> <nil>]") {
+		t.Error(s)
+	}
+	if string(get1.Value) != "bar" {
+		t.Error(get1.Value)
+	}
+	if get2.Value != nil {
+		t.Error(get2.Value)
+	}
+	if writes != 1 {
+		t.Error(writes)
+	}
+	if s := s.CommandsString(); s != `["SET foo bar" "GET foo" "GET baz"]` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice_RedisError(t *testing.T) {
+	s := NewTestingServer(t, "")
+	p := &Cache{Address: s.Address()}
+	defer p.Close()
+	get := &c.CacheGet{Key: "foo"}
+	set := c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}
+	replies, err := p.getPool().do([][][]byte{{[]byte("FOO")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result(get, replies[0]); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Redis Error: ERR unknown command 'FOO'` {
+		t.Error(s)
+	}
+	// The connection is still usable after an error reply.
+	if errs := p.AccessSlice([]c.CacheAccess{set, get}); errs[0] != nil || errs[1] != nil {
+		t.Error(errs)
+	} else if string(get.Value) != "bar" {
+		t.Error(get.Value)
+	}
+	if s.Connections() != 1 {
+		t.Error(s.Connections())
+	}
+}
+
+func TestAccess_Auth(t *testing.T) {
+	s := NewTestingServer(t, "secret")
+	p := &Cache{Address: s.Address(), Options: Options{Password: "secret", Database: 2}}
+	defer p.Close()
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	if v, ok := s.Get(2, "foo"); !ok || string(v) != "bar" {
+		t.Error(v, ok)
+	}
+	if s := s.CommandsString(); s != `["AUTH secret" "SELECT 2" "SET foo bar"]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_AuthError(t *testing.T) {
+	s := NewTestingServer(t, "secret")
+	p := &Cache{Address: s.Address(), Options: Options{Username: "user", Password: "wrong"}}
+	defer p.Close()
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Redis Error: WRONGPASS invalid password` {
+		t.Error(s)
+	}
+	if s := s.CommandsString(); s != `["AUTH user wrong"]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_DialError(t *testing.T) {
+	p := &Cache{Address: "127.0.0.1:1", Options: Options{
+		Dial: func(address string) (net.Conn, error) {
+			return nil, fmt.Errorf("cannot dial %v", address)
+		},
+	}}
+	if errs := p.AccessSlice([]c.CacheAccess{&c.CacheGet{Key: "foo"}, c.CacheSet{Key: "foo"}}); fmt.Sprint(errs) != `[cannot dial 127.0.0.1:1 cannot dial 127.0.0.1:1]` {
+		t.Error(errs)
+	}
+}
+
+func TestAccess_Pool(t *testing.T) {
+	s := NewTestingServer(t, "")
+	p := &Cache{Address: s.Address(), Options: Options{MaxIdle: 2}}
+	defer p.Close()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				key := c.CacheKey(fmt.Sprint(i, "-", j))
+				get := &c.CacheGet{Key: key}
+				errs := p.AccessSlice([]c.CacheAccess{c.CacheSet{Key: key, Value: c.CacheValue(key)}, get})
+				if errs[0] != nil || errs[1] != nil {
+					t.Error(errs)
+				} else if string(get.Value) != string(key) {
+					t.Error(get.Value)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := len(p.getPool().idle); n > 2 {
+		t.Error(n)
+	}
+}
@@ -0,0 +1,309 @@
+package heptane
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// Slots is the number of hash slots of a redis cluster.
+const Slots = 16384
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+func crc16(b []byte) uint16 {
+	crc := uint16(0)
+	for _, x := range b {
+		crc ^= uint16(x) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Slot returns the hash slot of the given CacheKey. If the key contains a non
+// empty hash tag between braces only the hash tag is hashed, so keys with the
+// same hash tag are in the same slot.
+func Slot(k c.CacheKey) int {
+	s := string(k)
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		if j := strings.IndexByte(s[i+1:], '}'); j > 0 {
+			s = s[i+1 : i+1+j]
+		}
+	}
+	return int(crc16([]byte(s)) % Slots)
+}
+
+// Cluster implements CacheProvider over a redis cluster. The map from hash
+// slots to nodes is discovered with CLUSTER SLOTS on the first access, updated
+// with the MOVED redirects and discovered again after a connection error.
+type Cluster struct {
+	// Addresses are the host:port of some nodes of the cluster, used to
+	// discover the others.
+	Addresses []string
+	// Options contains the connection parameters of every node.
+	Options Options
+	// MaxRedirects is the maximum number of MOVED or ASK redirects followed
+	// by each command. Zero means 5 and negative values mean none.
+	MaxRedirects int
+
+	m     sync.Mutex
+	slots []string
+	stale bool
+	pools map[string]*pool
+}
+
+// getPool returns the pool of the node with the given address.
+func (p *Cluster) getPool(address string) *pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.pools == nil {
+		p.pools = map[string]*pool{}
+	}
+	if p.pools[address] == nil {
+		p.pools[address] = &pool{address: address, options: &p.Options}
+	}
+	return p.pools[address]
+}
+
+// discover reads the map from hash slots to nodes from the first node that
+// replies to CLUSTER SLOTS.
+func (p *Cluster) discover() error {
+	p.m.Lock()
+	addresses := append([]string(nil), p.Addresses...)
+	for address := range p.pools {
+		addresses = append(addresses, address)
+	}
+	p.m.Unlock()
+	err := error(nil)
+	for _, address := range addresses {
+		slots := []string(nil)
+		if slots, err = p.clusterSlots(address); err == nil {
+			p.m.Lock()
+			p.slots = slots
+			p.stale = false
+			p.m.Unlock()
+			return nil
+		}
+	}
+	if err == nil {
+		err = NoClusterNodeError{}
+	}
+	return err
+}
+
+// clusterSlots sends CLUSTER SLOTS to a node and parses its reply.
+func (p *Cluster) clusterSlots(address string) ([]string, error) {
+	replies, err := p.getPool(address).do([][][]byte{{[]byte("CLUSTER"), []byte("SLOTS")}})
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := replies[0].(RedisError); ok {
+		return nil, err
+	}
+	ranges, ok := replies[0].([]interface{})
+	if !ok {
+		return nil, ProtocolError{replies[0]}
+	}
+	slots := make([]string, Slots)
+	for _, reply := range ranges {
+		// Each range is the first and last slot followed by the master
+		// and the replicas, each one an ip and a port.
+		rng, ok := reply.([]interface{})
+		if !ok || len(rng) < 3 {
+			return nil, ProtocolError{reply}
+		}
+		first, ok1 := rng[0].(int64)
+		last, ok2 := rng[1].(int64)
+		master, ok3 := rng[2].([]interface{})
+		if !ok1 || !ok2 || !ok3 || len(master) < 2 || first < 0 || last >= Slots || first > last {
+			return nil, ProtocolError{reply}
+		}
+		ip, ok1 := master[0].([]byte)
+		port, ok2 := master[1].(int64)
+		if !ok1 || !ok2 {
+			return nil, ProtocolError{reply}
+		}
+		host := string(ip)
+		if host == "" {
+			// An unknown ip means the node that replied.
+			host, _, _ = net.SplitHostPort(address)
+		}
+		node := net.JoinHostPort(host, strconv.FormatInt(port, 10))
+		for i := first; i <= last; i++ {
+			slots[i] = node
+		}
+	}
+	return slots, nil
+}
+
+// node returns the address of the node serving the given hash slot.
+func (p *Cluster) node(slot int) (string, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.slots[slot] == "" {
+		return "", UnassignedSlotError{slot}
+	}
+	return p.slots[slot], nil
+}
+
+// clusterCommand is a command sent to a node of the cluster.
+type clusterCommand struct {
+	index   int
+	slot    int
+	args    [][]byte
+	address string
+	asking  bool
+	reply   interface{}
+	err     error
+}
+
+// redirect parses a MOVED or ASK error reply. A reply with a slot out of range
+// is not a redirection.
+func redirect(reply interface{}) (moved bool, slot int, address string, ok bool) {
+	err, ok := reply.(RedisError)
+	if !ok {
+		return
+	}
+	fields := strings.Fields(err.Message)
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return false, 0, "", false
+	}
+	slot, e := strconv.Atoi(fields[1])
+	if e != nil || slot < 0 || slot >= Slots {
+		return false, 0, "", false
+	}
+	return fields[0] == "MOVED", slot, fields[2], true
+}
+
+// send sends the commands to their nodes, the commands of each node in a
+// single round trip and the nodes in parallel.
+func (p *Cluster) send(ccs []*clusterCommand) {
+	nodes := map[string][]*clusterCommand{}
+	for _, cc := range ccs {
+		nodes[cc.address] = append(nodes[cc.address], cc)
+	}
+	wg := sync.WaitGroup{}
+	for address, ccs := range nodes {
+		wg.Add(1)
+		go func(address string, ccs []*clusterCommand) {
+			defer wg.Done()
+			commands := [][][]byte(nil)
+			for _, cc := range ccs {
+				if cc.asking {
+					commands = append(commands, [][]byte{[]byte("ASKING")})
+				}
+				commands = append(commands, cc.args)
+			}
+			replies, err := p.getPool(address).do(commands)
+			if err != nil {
+				p.m.Lock()
+				p.stale = true
+				p.m.Unlock()
+			}
+			j := 0
+			for _, cc := range ccs {
+				if cc.asking {
+					j++
+				}
+				if err != nil {
+					cc.err = err
+				} else {
+					cc.reply = replies[j]
+				}
+				j++
+			}
+		}(address, ccs)
+	}
+	wg.Wait()
+}
+
+// Access implements CacheProvider.
+func (p *Cluster) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The commands are split per node, the
+// commands of each node are sent in a single round trip and the nodes are
+// accessed in parallel.
+func (p *Cluster) AccessSlice(aa []c.CacheAccess) []error {
+	errs := make([]error, len(aa))
+	p.m.Lock()
+	discover := p.slots == nil || p.stale
+	p.m.Unlock()
+	if discover {
+		if err := p.discover(); err != nil {
+			for i, a := range aa {
+				if _, err2 := command(a); err2 != nil {
+					errs[i] = err2
+				} else {
+					errs[i] = err
+				}
+			}
+			return errs
+		}
+	}
+	pending := []*clusterCommand(nil)
+	ccs := []*clusterCommand(nil)
+	for i, a := range aa {
+		args, err := command(a)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		cc := &clusterCommand{index: i, slot: Slot(c.CacheKey(args[1])), args: args}
+		if cc.address, cc.err = p.node(cc.slot); cc.err == nil {
+			pending = append(pending, cc)
+		}
+		ccs = append(ccs, cc)
+	}
+	maxRedirects := p.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = 5
+	} else if maxRedirects < 0 {
+		maxRedirects = 0
+	}
+	for redirects := 0; pending != nil; redirects++ {
+		p.send(pending)
+		next := []*clusterCommand(nil)
+		for _, cc := range pending {
+			moved, slot, address, ok := redirect(cc.reply)
+			if cc.err != nil || !ok || redirects >= maxRedirects {
+				continue
+			}
+			if moved {
+				p.m.Lock()
+				p.slots[slot] = address
+				p.m.Unlock()
+			}
+			cc.address = address
+			cc.asking = !moved
+			next = append(next, cc)
+		}
+		pending = next
+	}
+	for _, cc := range ccs {
+		if cc.err != nil {
+			errs[cc.index] = cc.err
+		} else {
+			errs[cc.index] = result(aa[cc.index], cc.reply)
+		}
+	}
+	return errs
+}
+
+// Close closes the idle connections to every node.
+func (p *Cluster) Close() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, pool := range p.pools {
+		pool.close()
+	}
+}
@@ -0,0 +1,287 @@
+package heptane
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// TestingCluster is a fake redis cluster of TestingServers, with the hash
+// slots split evenly among them.
+type TestingCluster struct {
+	Servers []*TestingServer
+
+	m         sync.Mutex
+	slots     [Slots]int
+	migrating map[int]int
+}
+
+func NewTestingCluster(t *testing.T, n int) *TestingCluster {
+	tc := &TestingCluster{migrating: map[int]int{}}
+	for i := 0; i < n; i++ {
+		s := NewTestingServer(t, "")
+		s.m.Lock()
+		s.cluster = tc
+		s.m.Unlock()
+		tc.Servers = append(tc.Servers, s)
+	}
+	for slot := range tc.slots {
+		tc.slots[slot] = slot * n / Slots
+	}
+	return tc
+}
+
+// Owner returns the index of the server serving the hash slot of the key.
+func (tc *TestingCluster) Owner(key c.CacheKey) int {
+	tc.m.Lock()
+	defer tc.m.Unlock()
+	return tc.slots[Slot(key)]
+}
+
+// Move assigns the hash slot of the key to another server.
+func (tc *TestingCluster) Move(key c.CacheKey, i int) {
+	tc.m.Lock()
+	defer tc.m.Unlock()
+	tc.slots[Slot(key)] = i
+	delete(tc.migrating, Slot(key))
+}
+
+// Migrate starts the migration of the hash slot of the key to another server.
+func (tc *TestingCluster) Migrate(key c.CacheKey, i int) {
+	tc.m.Lock()
+	defer tc.m.Unlock()
+	tc.migrating[Slot(key)] = i
+}
+
+// redirect returns the MOVED or ASK error the server replies to a command on
+// the key, if any.
+func (tc *TestingCluster) redirect(s *TestingServer, key string, stored, asking bool) string {
+	tc.m.Lock()
+	defer tc.m.Unlock()
+	slot := Slot(c.CacheKey(key))
+	owner := tc.Servers[tc.slots[slot]]
+	if i, ok := tc.migrating[slot]; ok {
+		if owner == s && !stored {
+			return fmt.Sprintf("ASK %v %v", slot, tc.Servers[i].Address())
+		}
+		if tc.Servers[i] == s && asking {
+			return ""
+		}
+	}
+	if owner != s {
+		return fmt.Sprintf("MOVED %v %v", slot, owner.Address())
+	}
+	return ""
+}
+
+// writeSlots writes the reply to CLUSTER SLOTS.
+func (tc *TestingCluster) writeSlots(w *bufio.Writer) {
+	tc.m.Lock()
+	defer tc.m.Unlock()
+	ranges := [][3]int(nil)
+	for slot, i := range tc.slots {
+		if n := len(ranges); n > 0 && ranges[n-1][2] == i {
+			ranges[n-1][1] = slot
+		} else {
+			ranges = append(ranges, [3]int{slot, slot, i})
+		}
+	}
+	fmt.Fprintf(w, "*%v\r\n", len(ranges))
+	for _, rng := range ranges {
+		host, port, _ := net.SplitHostPort(tc.Servers[rng[2]].Address())
+		fmt.Fprintf(w, "*3\r\n:%v\r\n:%v\r\n*2\r\n$%v\r\n%v\r\n:%v\r\n", rng[0], rng[1], len(host), host, port)
+	}
+}
+
+func TestSlot(t *testing.T) {
+	if crc := crc16([]byte("123456789")); crc != 0x31c3 {
+		t.Errorf("%x", crc)
+	}
+	for k, expected := range map[c.CacheKey]int{
+		"foo":                  12182,
+		"{user1000}.following": Slot("user1000"),
+		"{user1000}.followers": Slot("user1000"),
+		"foo{}{bar}":           int(crc16([]byte("foo{}{bar}")) % Slots),
+		"foo{{bar}}zap":        Slot("{bar"),
+		"foo{bar}{zap}":        Slot("bar"),
+	} {
+		if slot := Slot(k); slot != expected {
+			t.Error(k, slot, expected)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	for message, expected := range map[string]string{
+		"MOVED 3999 127.0.0.1:6381": "true 3999 127.0.0.1:6381 true",
+		"ASK 0 127.0.0.1:6381":      "false 0 127.0.0.1:6381 true",
+		"MOVED 16384 host:1":        "false 0  false",
+		"MOVED 99999 host:1":        "false 0  false",
+		"ASK -1 host:1":             "false 0  false",
+		"ERR unknown":               "false 0  false",
+	} {
+		moved, slot, address, ok := redirect(RedisError{message})
+		if s := fmt.Sprint(moved, " ", slot, " ", address, " ", ok); s != expected {
+			t.Error(message, s)
+		}
+	}
+}
+
+func TestCluster_AccessSlice(t *testing.T) {
+	tc := NewTestingCluster(t, 3)
+	p := &Cluster{Addresses: []string{tc.Servers[1].Address()}}
+	defer p.Close()
+	sets := []c.CacheAccess(nil)
+	gets := []c.CacheAccess(nil)
+	for i := 0; i < 30; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		sets = append(sets, c.CacheSet{Key: k, Value: c.CacheValue(k)})
+		gets = append(gets, &c.CacheGet{Key: k})
+	}
+	for _, aa := range [][]c.CacheAccess{sets, gets} {
+		for _, err := range p.AccessSlice(aa) {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	for _, a := range gets {
+		a := a.(*c.CacheGet)
+		if string(a.Value) != string(a.Key) {
+			t.Error(a.Key, a.Value)
+		}
+		s := tc.Servers[tc.Owner(a.Key)]
+		if v, ok := s.Get(0, string(a.Key)); !ok || string(v) != string(a.Key) {
+			t.Error(a.Key, v, ok)
+		}
+	}
+	for i, s := range tc.Servers {
+		if n := s.Connections(); n != 1 {
+			t.Error(i, n)
+		}
+		commands := s.CommandsString()
+		if i == 1 {
+			if !strings.HasPrefix(commands, `["CLUSTER SLOTS" `) {
+				t.Error(commands)
+			}
+		} else if strings.Contains(commands, "CLUSTER") {
+			t.Error(commands)
+		}
+	}
+}
+
+func TestCluster_Moved(t *testing.T) {
+	tc := NewTestingCluster(t, 3)
+	p := &Cluster{Addresses: []string{tc.Servers[0].Address()}}
+	defer p.Close()
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	o := tc.Owner("foo")
+	n := (o + 1) % 3
+	tc.Servers[n].Set(0, "foo", []byte("bar"))
+	tc.Servers[o].Set(0, "foo", nil)
+	tc.Move("foo", n)
+	tc.Servers[o].ResetCommands()
+	tc.Servers[n].ResetCommands()
+	for i := 0; i < 2; i++ {
+		a := &c.CacheGet{Key: "foo"}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if string(a.Value) != "bar" {
+			t.Error(a.Value)
+		}
+	}
+	// The second access is sent to the new node directly.
+	if s := tc.Servers[o].CommandsString(); s != `["GET foo"]` {
+		t.Error(s)
+	}
+	if s := tc.Servers[n].CommandsString(); s != `["GET foo" "GET foo"]` {
+		t.Error(s)
+	}
+}
+
+func TestCluster_Ask(t *testing.T) {
+	tc := NewTestingCluster(t, 3)
+	p := &Cluster{Addresses: []string{tc.Servers[0].Address()}}
+	defer p.Close()
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	o := tc.Owner("foo")
+	n := (o + 1) % 3
+	tc.Migrate("foo", n)
+	tc.Servers[n].Set(0, "foo", []byte("bar"))
+	tc.Servers[o].Set(0, "foo", nil)
+	tc.Servers[o].ResetCommands()
+	tc.Servers[n].ResetCommands()
+	for i := 0; i < 2; i++ {
+		a := &c.CacheGet{Key: "foo"}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if string(a.Value) != "bar" {
+			t.Error(a.Value)
+		}
+	}
+	// The slot map is not updated by an ASK redirect.
+	if s := tc.Servers[o].CommandsString(); s != `["GET foo" "GET foo"]` {
+		t.Error(s)
+	}
+	if s := tc.Servers[n].CommandsString(); s != `["ASKING" "GET foo" "ASKING" "GET foo"]` {
+		t.Error(s)
+	}
+}
+
+func TestCluster_MaxRedirects(t *testing.T) {
+	tc := NewTestingCluster(t, 2)
+	p := &Cluster{Addresses: []string{tc.Servers[0].Address()}, MaxRedirects: -1}
+	defer p.Close()
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	o := tc.Owner("foo")
+	tc.Move("foo", 1-o)
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != fmt.Sprintf("Redis Error: MOVED %v %v", Slot("foo"), tc.Servers[1-o].Address()) {
+		t.Error(s)
+	}
+}
+
+func TestCluster_NoNode(t *testing.T) {
+	p := &Cluster{}
+	if errs := p.AccessSlice([]c.CacheAccess{&c.CacheGet{Key: "foo"}, c.CacheGet{}}); fmt.Sprint(errs[0]) != `No Node in Cluster` {
+		t.Error(errs)
+	} else if _, ok := errs[1].(UnsupportedCacheAccessTypeError); !ok {
+		t.Error(errs[1])
+	}
+}
+
+func TestCluster_Rediscover(t *testing.T) {
+	tc := NewTestingCluster(t, 2)
+	p := &Cluster{Addresses: []string{tc.Servers[0].Address(), tc.Servers[1].Address()}}
+	defer p.Close()
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	// A connection error marks the slot map as stale.
+	p.getPool(tc.Servers[tc.Owner("foo")].Address()).close()
+	tc.Servers[tc.Owner("foo")].listener.Close()
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err == nil {
+		t.Error(err)
+	}
+	p.m.Lock()
+	stale := p.stale
+	p.m.Unlock()
+	if !stale {
+		t.Error(stale)
+	}
+	if err := p.Access(&c.CacheGet{Key: "bar"}); err != nil && tc.Owner("bar") != tc.Owner("foo") {
+		t.Error(err)
+	}
+}
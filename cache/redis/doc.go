@@ -0,0 +1,14 @@
+/*
+Implementation of CacheProvider relying on redis.
+
+Cache speaks the RESP protocol with a redis server over a pool of connections.
+Each CacheGet is a GET and each CacheSet a SET, or a DEL when its CacheValue is
+nil, so the following CacheGet is a miss. AccessSlice sends all its commands
+in a single round trip.
+
+Cluster does the same over a redis cluster. The CacheKeys are mapped to hash
+slots and the hash slots to nodes, as discovered with CLUSTER SLOTS, and the
+MOVED and ASK redirects are followed. AccessSlice sends the commands of each
+node in a single round trip, accessing the nodes in parallel.
+*/
+package heptane
@@ -0,0 +1,54 @@
+package heptane
+
+import (
+	"fmt"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// UnsupportedCacheAccessTypeError is produced when the type of a CacheAccess
+// is not supported. Current supported types are CacheGet and CacheSet.
+type UnsupportedCacheAccessTypeError struct {
+	CacheAccess c.CacheAccess
+}
+
+func (e UnsupportedCacheAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported CacheAccess Type: %#v", e.CacheAccess)
+}
+
+// RedisError is produced when the server replies with an error.
+type RedisError struct {
+	Message string
+}
+
+func (e RedisError) Error() string {
+	return fmt.Sprintf("Redis Error: %v", e.Message)
+}
+
+// ProtocolError is produced when the server sends a malformed or unexpected
+// reply.
+type ProtocolError struct {
+	Reply interface{}
+}
+
+func (e ProtocolError) Error() string {
+	return fmt.Sprintf("Protocol Error: unexpected reply %q", e.Reply)
+}
+
+// NoClusterNodeError is produced when a Cluster has no Addresses to discover
+// its nodes.
+type NoClusterNodeError struct{}
+
+func (e NoClusterNodeError) Error() string {
+	return "No Node in Cluster"
+}
+
+// UnassignedSlotError is produced when no node of a Cluster serves the hash
+// slot of a CacheKey.
+type UnassignedSlotError struct {
+	Slot int
+}
+
+func (e UnassignedSlotError) Error() string {
+	return fmt.Sprintf("Unassigned Slot in Cluster: %v", e.Slot)
+}
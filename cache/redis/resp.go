@@ -0,0 +1,237 @@
+package heptane
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// writeCommand writes a command in the RESP protocol, as an array of bulk
+// strings.
+func writeCommand(w *bufio.Writer, args ...[]byte) {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+	for _, arg := range args {
+		w.WriteByte('$')
+		w.WriteString(strconv.Itoa(len(arg)))
+		w.WriteString("\r\n")
+		w.Write(arg)
+		w.WriteString("\r\n")
+	}
+}
+
+// readLine reads a line of the RESP protocol without its terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", ProtocolError{line}
+	}
+	return line[:len(line)-2], nil
+}
+
+// readReply reads a reply of the RESP protocol: a string for simple strings, a
+// RedisError for errors, an int64 for integers, a []byte for bulk strings
+// (nil for the null bulk string) and an []interface{} for arrays (nil for the
+// null array). Errors are returned as replies, not as errors, so the rest of
+// the replies of a pipeline can be read.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ProtocolError{line}
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return RedisError{line[1:]}, nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, ProtocolError{line}
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ProtocolError{line}
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		q := make([]byte, n+2)
+		if _, err := io.ReadFull(r, q); err != nil {
+			return nil, err
+		}
+		return q[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ProtocolError{line}
+		}
+		if n < 0 {
+			return []interface{}(nil), nil
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, ProtocolError{line}
+}
+
+// conn is a connection to a server.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// do sends several commands in a single round trip and returns their replies.
+// An error means the connection is no longer usable.
+func (c *conn) do(timeout time.Duration, commands [][][]byte) ([]interface{}, error) {
+	if timeout > 0 {
+		c.SetDeadline(time.Now().Add(timeout))
+	}
+	for _, args := range commands {
+		writeCommand(c.w, args...)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(commands))
+	for i := range replies {
+		reply, err := readReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Options contains the connection parameters of a redis server.
+type Options struct {
+	// Username, if not empty, is sent with Password in the AUTH command of
+	// every new connection.
+	Username string
+	// Password, if not empty, is sent with the AUTH command on every new
+	// connection.
+	Password string
+	// Database, if not zero, is selected with the SELECT command on every
+	// new connection.
+	Database int
+	// MaxIdle is the maximum number of idle connections kept open for each
+	// server. Values lower than 1 mean 1.
+	MaxIdle int
+	// Timeout, if not zero, is the maximum duration of each round trip.
+	Timeout time.Duration
+	// Dial, if not nil, opens the connections instead of net.Dial.
+	Dial func(address string) (net.Conn, error)
+}
+
+// pool keeps the idle connections to a server.
+type pool struct {
+	address string
+	options *Options
+	m       sync.Mutex
+	idle    []*conn
+}
+
+// get returns an idle connection or a new one.
+func (p *pool) get() (*conn, error) {
+	p.m.Lock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.m.Unlock()
+		return c, nil
+	}
+	p.m.Unlock()
+	dial := p.options.Dial
+	if dial == nil {
+		dial = func(address string) (net.Conn, error) {
+			return net.Dial("tcp", address)
+		}
+	}
+	nc, err := dial(p.address)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{nc, bufio.NewReader(nc), bufio.NewWriter(nc)}
+	commands := [][][]byte(nil)
+	switch {
+	case p.options.Username != "":
+		commands = append(commands, [][]byte{[]byte("AUTH"), []byte(p.options.Username), []byte(p.options.Password)})
+	case p.options.Password != "":
+		commands = append(commands, [][]byte{[]byte("AUTH"), []byte(p.options.Password)})
+	}
+	if p.options.Database != 0 {
+		commands = append(commands, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(p.options.Database))})
+	}
+	if commands == nil {
+		return c, nil
+	}
+	replies, err := c.do(p.options.Timeout, commands)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(RedisError); ok {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// put returns a connection to the pool, or closes it if it failed or the pool
+// is full.
+func (p *pool) put(c *conn, err error) {
+	if err == nil {
+		p.m.Lock()
+		if len(p.idle) < p.options.MaxIdle || len(p.idle) < 1 {
+			p.idle = append(p.idle, c)
+			c = nil
+		}
+		p.m.Unlock()
+	}
+	if c != nil {
+		c.Close()
+	}
+}
+
+// do sends several commands in a single round trip on a connection of the
+// pool.
+func (p *pool) do(commands [][][]byte) ([]interface{}, error) {
+	c, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	replies, err := c.do(p.options.Timeout, commands)
+	p.put(c, err)
+	return replies, err
+}
+
+// close closes the idle connections.
+func (p *pool) close() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,177 @@
+package heptane
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestingServer is a fake redis server supporting AUTH, SELECT, GET, SET and
+// DEL, and ASKING and CLUSTER SLOTS when it is a node of a TestingCluster.
+type TestingServer struct {
+	Password string
+
+	m           sync.Mutex
+	listener    net.Listener
+	dbs         map[int]map[string][]byte
+	connections int
+	cluster     *TestingCluster
+	Commands    []string
+}
+
+func NewTestingServer(t *testing.T, password string) *TestingServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &TestingServer{Password: password, listener: l, dbs: map[int]map[string][]byte{}}
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.m.Lock()
+			s.connections++
+			s.m.Unlock()
+			go s.serve(nc)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *TestingServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *TestingServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+	db := 0
+	authenticated := s.Password == ""
+	asking := false
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		args := []string(nil)
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+		s.m.Lock()
+		s.Commands = append(s.Commands, strings.Join(args, " "))
+		redirect := ""
+		if s.cluster != nil && len(args) > 1 && (args[0] == "GET" || args[0] == "SET" || args[0] == "DEL") {
+			_, stored := s.dbs[db][args[1]]
+			redirect = s.cluster.redirect(s, args[1], stored, asking)
+		}
+		if args[0] != "ASKING" {
+			asking = false
+		}
+		switch {
+		case args[0] == "AUTH":
+			if args[len(args)-1] == s.Password {
+				authenticated = true
+				w.WriteString("+OK\r\n")
+			} else {
+				w.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case !authenticated:
+			w.WriteString("-NOAUTH Authentication required.\r\n")
+		case redirect != "":
+			fmt.Fprintf(w, "-%s\r\n", redirect)
+		case args[0] == "ASKING" && s.cluster != nil:
+			asking = true
+			w.WriteString("+OK\r\n")
+		case args[0] == "CLUSTER" && s.cluster != nil:
+			s.cluster.writeSlots(w)
+		case args[0] == "SELECT":
+			db, _ = strconv.Atoi(args[1])
+			w.WriteString("+OK\r\n")
+		case args[0] == "GET":
+			if v, ok := s.dbs[db][args[1]]; ok {
+				fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				w.WriteString("$-1\r\n")
+			}
+		case args[0] == "SET":
+			if s.dbs[db] == nil {
+				s.dbs[db] = map[string][]byte{}
+			}
+			s.dbs[db][args[1]] = []byte(args[2])
+			w.WriteString("+OK\r\n")
+		case args[0] == "DEL":
+			_, ok := s.dbs[db][args[1]]
+			delete(s.dbs[db], args[1])
+			if ok {
+				w.WriteString(":1\r\n")
+			} else {
+				w.WriteString(":0\r\n")
+			}
+		default:
+			fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.m.Unlock()
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *TestingServer) Set(db int, key string, value []byte) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.dbs[db] == nil {
+		s.dbs[db] = map[string][]byte{}
+	}
+	if value == nil {
+		delete(s.dbs[db], key)
+	} else {
+		s.dbs[db][key] = value
+	}
+}
+
+func (s *TestingServer) Get(db int, key string) ([]byte, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	v, ok := s.dbs[db][key]
+	return v, ok
+}
+
+func (s *TestingServer) CommandsString() string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return fmt.Sprintf("%q", s.Commands)
+}
+
+func (s *TestingServer) ResetCommands() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.Commands = nil
+}
+
+// countingConn counts the writes to a net.Conn.
+type countingConn struct {
+	net.Conn
+	writes *int
+}
+
+func (c countingConn) Write(b []byte) (int, error) {
+	*c.writes++
+	return c.Conn.Write(b)
+}
+
+func (s *TestingServer) Connections() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.connections
+}
@@ -0,0 +1,196 @@
+package heptane
+
+import (
+	"sync"
+
+	h "github.com/heptanes/heptane"
+	c "github.com/heptanes/heptane/cache"
+	rg "github.com/heptanes/heptane/ring"
+)
+
+// Node is a replica of a Cache.
+type Node struct {
+	// Name identifies the Node in the Ring, so it must be unique and
+	// stable.
+	Name string
+	// Weight is the relative share of the keys of the Node. Values lower
+	// than 1 mean 1.
+	Weight int
+	// CacheProvider stores the entries of the Node.
+	CacheProvider c.CacheProvider
+}
+
+// Cache implements CacheProvider. The Nodes must not be changed after the
+// first access.
+type Cache struct {
+	// Nodes are the CacheProviders among which the entries are replicated.
+	Nodes []Node
+	// Replicas is the number of Nodes storing each entry. Values lower than
+	// 1 or greater than the number of Nodes mean all the Nodes.
+	Replicas int
+	// WriteQuorum is the number of replicas where a CacheSet must succeed,
+	// the first replica of its key among them. Values lower than 1 or
+	// greater than Replicas mean all the replicas.
+	WriteQuorum int
+	// Failures, if not nil, is called with each failure of a replica, even
+	// if the CacheAccess succeeds in another one.
+	Failures func(h.CacheProviderAccessError)
+
+	once sync.Once
+	ring *rg.Ring
+}
+
+func (p *Cache) init() {
+	p.once.Do(func() {
+		nodes := make([]rg.Node, len(p.Nodes))
+		for i, n := range p.Nodes {
+			nodes[i] = rg.Node{Name: n.Name, Weight: n.Weight}
+		}
+		p.ring = rg.New(nodes, 0)
+	})
+}
+
+func key(a c.CacheAccess) (c.CacheKey, bool) {
+	switch a := a.(type) {
+	case *c.CacheGet:
+		return a.Key, true
+	case c.CacheSet:
+		return a.Key, true
+	case *c.CacheSet:
+		return a.Key, true
+	}
+	return "", false
+}
+
+// call is a CacheAccess sent to a Node.
+type call struct {
+	index  int
+	access c.CacheAccess
+	err    error
+}
+
+// run sends the calls of each Node in a single AccessSlice, the Nodes in
+// parallel.
+func (p *Cache) run(calls [][]*call) {
+	wg := sync.WaitGroup{}
+	for n, cs := range calls {
+		if cs == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(n int, cs []*call) {
+			defer wg.Done()
+			aa := make([]c.CacheAccess, len(cs))
+			for j, cl := range cs {
+				aa[j] = cl.access
+			}
+			for j, err := range p.Nodes[n].CacheProvider.AccessSlice(aa) {
+				cs[j].err = err
+			}
+		}(n, cs)
+	}
+	wg.Wait()
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The CacheAccesses of each Node are
+// performed in a single AccessSlice, the Nodes in parallel. The failed
+// CacheGets are retried on their next replica in another round.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	p.init()
+	replicas := p.Replicas
+	if replicas < 1 || replicas > len(p.Nodes) {
+		replicas = len(p.Nodes)
+	}
+	errs := make([]error, len(aa))
+	nodes := make([][]int, len(aa))
+	attempts := make([]int, len(aa))
+	failures := make([][]error, len(aa))
+	primary := make([]bool, len(aa))
+	calls := make([][]*call, len(p.Nodes))
+	for i, a := range aa {
+		k, ok := key(a)
+		if !ok {
+			errs[i] = UnsupportedCacheAccessTypeError{a}
+			continue
+		}
+		if nodes[i] = p.ring.Nodes([]byte(k), replicas); nodes[i] == nil {
+			errs[i] = NoNodeError{}
+			continue
+		}
+		if _, ok := a.(*c.CacheGet); ok {
+			calls[nodes[i][0]] = append(calls[nodes[i][0]], &call{index: i, access: a})
+			continue
+		}
+		primary[i] = true
+		for _, n := range nodes[i] {
+			calls[n] = append(calls[n], &call{index: i, access: a})
+		}
+	}
+	for {
+		p.run(calls)
+		next := make([][]*call, len(p.Nodes))
+		retries := false
+		for n, cs := range calls {
+			for _, cl := range cs {
+				if cl.err == nil {
+					continue
+				}
+				i := cl.index
+				err := h.CacheProviderAccessError{Access: aa[i], Err: ReplicaError{p.Nodes[n].Name, cl.err}}
+				failures[i] = append(failures[i], err)
+				if p.Failures != nil {
+					p.Failures(err)
+				}
+				if _, ok := aa[i].(*c.CacheGet); !ok {
+					if n == nodes[i][0] {
+						primary[i] = false
+					}
+					continue
+				}
+				if attempts[i]++; attempts[i] < len(nodes[i]) {
+					m := nodes[i][attempts[i]]
+					next[m] = append(next[m], &call{index: i, access: aa[i]})
+					retries = true
+				}
+			}
+		}
+		if !retries {
+			break
+		}
+		calls = next
+	}
+	for i, a := range aa {
+		if errs[i] != nil || failures[i] == nil {
+			continue
+		}
+		if _, ok := a.(*c.CacheGet); ok {
+			if attempts[i] == len(nodes[i]) {
+				errs[i] = collapse(failures[i])
+			}
+			continue
+		}
+		quorum := p.WriteQuorum
+		if quorum < 1 || quorum > len(nodes[i]) {
+			quorum = len(nodes[i])
+		}
+		if succeeded := len(nodes[i]) - len(failures[i]); succeeded < quorum {
+			errs[i] = QuorumError{quorum, succeeded, failures[i]}
+		} else if !primary[i] {
+			// The CacheGets would still read the previous entry.
+			errs[i] = PrimaryError{p.Nodes[nodes[i][0]].Name, failures[i]}
+		}
+	}
+	return errs
+}
+
+func collapse(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return h.MultipleErrors{Errors: errs}
+}
@@ -0,0 +1,179 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	h "github.com/heptanes/heptane"
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+)
+
+// TestingCache is an in-process cache that fails every CacheAccess when Down.
+type TestingCache struct {
+	cm.Cache
+	m    sync.Mutex
+	Down bool
+}
+
+func (p *TestingCache) SetDown(down bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.Down = down
+}
+
+func (p *TestingCache) AccessSlice(aa []c.CacheAccess) []error {
+	p.m.Lock()
+	down := p.Down
+	p.m.Unlock()
+	if !down {
+		return p.Cache.AccessSlice(aa)
+	}
+	errs := make([]error, len(aa))
+	for i := range aa {
+		errs[i] = errors.New("down")
+	}
+	return errs
+}
+
+func TestingReplicated(replicas, quorum int) (*Cache, []*TestingCache) {
+	p := &Cache{Replicas: replicas, WriteQuorum: quorum}
+	tcs := []*TestingCache(nil)
+	for _, n := range []string{"a", "b", "c"} {
+		tc := &TestingCache{}
+		tcs = append(tcs, tc)
+		p.Nodes = append(p.Nodes, Node{Name: n, CacheProvider: tc})
+	}
+	return p, tcs
+}
+
+func TestAccess_Replicas(t *testing.T) {
+	p, tcs := TestingReplicated(2, 0)
+	for i := 0; i < 30; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		if err := p.Access(c.CacheSet{Key: k, Value: c.CacheValue(k)}); err != nil {
+			t.Error(err)
+		}
+	}
+	total := 0
+	for _, tc := range tcs {
+		total += tc.Statistics().Entries
+	}
+	if total != 60 {
+		t.Error(total)
+	}
+}
+
+func TestAccess_ReadFallback(t *testing.T) {
+	p, tcs := TestingReplicated(2, 0)
+	failures := []string(nil)
+	p.Failures = func(err h.CacheProviderAccessError) { failures = append(failures, err.Err.Error()) }
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	p.init()
+	nodes := p.ring.Nodes([]byte("foo"), 2)
+	tcs[nodes[0]].SetDown(true)
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprint(failures); s != fmt.Sprintf("[Replica %v Error: down]", p.Nodes[nodes[0]].Name) {
+		t.Error(s)
+	}
+	tcs[nodes[1]].SetDown(true)
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(h.MultipleErrors); !ok {
+		t.Error(err)
+	}
+}
+
+func TestAccess_WriteQuorum(t *testing.T) {
+	p, tcs := TestingReplicated(3, 2)
+	p.init()
+	nodes := p.ring.Nodes([]byte("foo"), 3)
+	tcs[nodes[1]].SetDown(true)
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	tcs[nodes[2]].SetDown(true)
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("baz")}); err == nil {
+		t.Error(err)
+	} else if e, ok := err.(QuorumError); !ok {
+		t.Error(err)
+	} else if e.Quorum != 2 || e.Succeeded != 1 || len(e.Errors) != 2 {
+		t.Error(e)
+	} else if _, ok := e.Errors[0].(h.CacheProviderAccessError); !ok {
+		t.Error(e.Errors[0])
+	}
+}
+
+func TestAccess_StalePrimary(t *testing.T) {
+	p, tcs := TestingReplicated(3, 2)
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	p.init()
+	nodes := p.ring.Nodes([]byte("foo"), 3)
+	tcs[nodes[0]].SetDown(true)
+	// The quorum is reached but the first replica keeps the previous entry.
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("baz")}); err == nil {
+		t.Error(err)
+	} else if e, ok := err.(PrimaryError); !ok {
+		t.Error(err)
+	} else if e.Node != p.Nodes[nodes[0]].Name || len(e.Errors) != 1 {
+		t.Error(e)
+	}
+	tcs[nodes[0]].SetDown(false)
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+}
+
+func TestAccess_Unsupported(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported CacheAccess Type: heptane.CacheGet{Key:"", Value:heptane.CacheValue(nil)}` {
+		t.Error(s)
+	}
+	if err := p.Access(&c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `No Node in Cache` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice(t *testing.T) {
+	p, tcs := TestingReplicated(2, 1)
+	aa := []c.CacheAccess(nil)
+	gets := []*c.CacheGet(nil)
+	for i := 0; i < 30; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		gets = append(gets, &c.CacheGet{Key: k})
+		aa = append(aa, c.CacheSet{Key: k, Value: c.CacheValue(k)}, gets[i])
+	}
+	tcs[2].SetDown(true)
+	for i, err := range p.AccessSlice(aa) {
+		if err == nil {
+			continue
+		}
+		// Only the CacheSets whose first replica is down fail.
+		if e, ok := err.(PrimaryError); !ok || i%2 != 0 || e.Node != "c" {
+			t.Error(err)
+		}
+	}
+	for _, a := range gets {
+		if string(a.Value) != string(a.Key) {
+			t.Error(a.Key, a.Value)
+		}
+	}
+}
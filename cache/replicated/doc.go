@@ -0,0 +1,13 @@
+/*
+Implementation of CacheProvider replicating the entries among other
+CacheProviders.
+
+Cache stores each entry in several of its Nodes, the replicas, selected with a
+consistent hashing Ring. Each CacheSet is sent to every replica and succeeds if
+at least WriteQuorum replicas succeed, the first one among them. Each CacheGet
+is sent to the first replica, and to the next one when it fails, so a failed
+Node does not turn its keys into misses, and a first replica that missed a
+CacheSet does not serve the previous entry unnoticed. The failures of the replicas are reported as
+CacheProviderAccessErrors.
+*/
+package heptane
@@ -0,0 +1,70 @@
+package heptane
+
+import (
+	"fmt"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// UnsupportedCacheAccessTypeError is produced when the type of a CacheAccess
+// is not supported. Current supported types are CacheGet and CacheSet.
+type UnsupportedCacheAccessTypeError struct {
+	CacheAccess c.CacheAccess
+}
+
+func (e UnsupportedCacheAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported CacheAccess Type: %#v", e.CacheAccess)
+}
+
+// NoNodeError is produced when a Cache has no Nodes.
+type NoNodeError struct{}
+
+func (e NoNodeError) Error() string {
+	return "No Node in Cache"
+}
+
+// ReplicaError is produced when the CacheProvider of a Node fails.
+type ReplicaError struct {
+	Node string
+	Err  error
+}
+
+func (e ReplicaError) Error() string {
+	return fmt.Sprintf("Replica %v Error: %v", e.Node, e.Err)
+}
+
+func (e ReplicaError) Unwrap() error {
+	return e.Err
+}
+
+// QuorumError is produced when a CacheSet does not succeed in enough replicas.
+// Errors contains the failures of the replicas.
+type QuorumError struct {
+	Quorum    int
+	Succeeded int
+	Errors    []error
+}
+
+func (e QuorumError) Error() string {
+	return fmt.Sprintf("Quorum %v not reached, %v replicas succeeded: %v", e.Quorum, e.Succeeded, e.Errors)
+}
+
+func (e QuorumError) Unwrap() []error {
+	return e.Errors
+}
+
+// PrimaryError is produced when a CacheSet reaches the quorum but fails in the
+// first replica of its key, which serves the CacheGets. Errors contains the
+// failures of the replicas.
+type PrimaryError struct {
+	Node   string
+	Errors []error
+}
+
+func (e PrimaryError) Error() string {
+	return fmt.Sprintf("Primary Replica %v failed: %v", e.Node, e.Errors)
+}
+
+func (e PrimaryError) Unwrap() []error {
+	return e.Errors
+}
@@ -0,0 +1,151 @@
+package heptane
+
+import (
+	"sync"
+
+	c "github.com/heptanes/heptane/cache"
+	rg "github.com/heptanes/heptane/ring"
+)
+
+// Node is a shard of a Cache.
+type Node struct {
+	// Name identifies the Node in the Ring, so it must be unique and
+	// stable.
+	Name string
+	// Weight is the relative share of the keys of the Node. Values lower
+	// than 1 mean 1.
+	Weight int
+	// CacheProvider stores the entries of the Node.
+	CacheProvider c.CacheProvider
+}
+
+// Cache implements CacheProvider and CacheInvalidator. The zero value has no
+// Nodes.
+type Cache struct {
+	m        sync.RWMutex
+	nodes    []Node
+	replicas int
+	ring     *rg.Ring
+}
+
+// New returns a Cache with the given Nodes, each one with the given number of
+// points in the Ring per unit of weight. Zero replicas mean DefaultReplicas.
+func New(nodes []Node, replicas int) *Cache {
+	p := &Cache{replicas: replicas}
+	p.SetNodes(nodes)
+	return p
+}
+
+// SetNodes replaces the Nodes of the Cache. Only the keys of the added or
+// removed Nodes are remapped.
+func (p *Cache) SetNodes(nodes []Node) {
+	rns := make([]rg.Node, len(nodes))
+	for i, n := range nodes {
+		rns[i] = rg.Node{Name: n.Name, Weight: n.Weight}
+	}
+	ring := rg.New(rns, p.replicas)
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.nodes = append([]Node(nil), nodes...)
+	p.ring = ring
+}
+
+// Nodes returns the current Nodes of the Cache.
+func (p *Cache) Nodes() []Node {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return append([]Node(nil), p.nodes...)
+}
+
+func key(a c.CacheAccess) (c.CacheKey, bool) {
+	switch a := a.(type) {
+	case *c.CacheGet:
+		return a.Key, true
+	case c.CacheSet:
+		return a.Key, true
+	case *c.CacheSet:
+		return a.Key, true
+	}
+	return "", false
+}
+
+// route returns the Nodes and the index of the Node of each CacheKey.
+func (p *Cache) route(keys []c.CacheKey) ([]Node, []int) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	indexes := make([]int, len(keys))
+	for i, k := range keys {
+		if p.ring == nil {
+			indexes[i] = -1
+		} else {
+			indexes[i] = p.ring.Node([]byte(k))
+		}
+	}
+	return p.nodes, indexes
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The CacheAccesses of each Node are
+// performed in a single AccessSlice, the Nodes in parallel.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	errs := make([]error, len(aa))
+	keys := make([]c.CacheKey, len(aa))
+	for i, a := range aa {
+		k, ok := key(a)
+		if !ok {
+			errs[i] = UnsupportedCacheAccessTypeError{a}
+		}
+		keys[i] = k
+	}
+	nodes, indexes := p.route(keys)
+	parts := make([][]c.CacheAccess, len(nodes))
+	partIndexes := make([][]int, len(nodes))
+	for i, a := range aa {
+		if errs[i] != nil {
+			continue
+		}
+		n := indexes[i]
+		if n < 0 {
+			errs[i] = NoNodeError{}
+			continue
+		}
+		parts[n] = append(parts[n], a)
+		partIndexes[n] = append(partIndexes[n], i)
+	}
+	wg := sync.WaitGroup{}
+	for n, part := range parts {
+		if part == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(n int, part []c.CacheAccess) {
+			defer wg.Done()
+			for j, err := range nodes[n].CacheProvider.AccessSlice(part) {
+				errs[partIndexes[n][j]] = err
+			}
+		}(n, part)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Invalidate implements CacheInvalidator. The keys are invalidated in the
+// Nodes whose CacheProviders implement CacheInvalidator.
+func (p *Cache) Invalidate(keys ...c.CacheKey) {
+	nodes, indexes := p.route(keys)
+	parts := make([][]c.CacheKey, len(nodes))
+	for i, k := range keys {
+		if n := indexes[i]; n >= 0 {
+			parts[n] = append(parts[n], k)
+		}
+	}
+	for n, part := range parts {
+		if ci, ok := nodes[n].CacheProvider.(c.CacheInvalidator); ok && part != nil {
+			ci.Invalidate(part...)
+		}
+	}
+}
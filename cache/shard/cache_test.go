@@ -0,0 +1,166 @@
+package heptane
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+)
+
+// TestingCache is an in-process cache counting the calls to AccessSlice.
+type TestingCache struct {
+	cm.Cache
+	m     sync.Mutex
+	Calls int
+}
+
+func (p *TestingCache) AccessSlice(aa []c.CacheAccess) []error {
+	p.m.Lock()
+	p.Calls++
+	p.m.Unlock()
+	return p.Cache.AccessSlice(aa)
+}
+
+func TestingNodes(names ...string) []Node {
+	nodes := []Node(nil)
+	for _, n := range names {
+		nodes = append(nodes, Node{Name: n, CacheProvider: &TestingCache{}})
+	}
+	return nodes
+}
+
+func TestAccess(t *testing.T) {
+	nodes := TestingNodes("a", "b", "c")
+	p := New(nodes, 0)
+	for i := 0; i < 100; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		if err := p.Access(c.CacheSet{Key: k, Value: c.CacheValue(k)}); err != nil {
+			t.Error(err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		a := &c.CacheGet{Key: k}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if string(a.Value) != string(k) {
+			t.Error(k, a.Value)
+		}
+	}
+	total := 0
+	for _, n := range nodes {
+		s := n.CacheProvider.(*TestingCache).Statistics()
+		if s.Entries < 20 {
+			t.Error(n.Name, s.Entries)
+		}
+		total += s.Entries
+	}
+	if total != 100 {
+		t.Error(total)
+	}
+}
+
+func TestAccess_Unsupported(t *testing.T) {
+	p := &Cache{}
+	if err := p.Access(c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported CacheAccess Type: heptane.CacheGet{Key:"", Value:heptane.CacheValue(nil)}` {
+		t.Error(s)
+	}
+	if err := p.Access(&c.CacheGet{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `No Node in Cache` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice(t *testing.T) {
+	nodes := TestingNodes("a", "b", "c")
+	p := New(nodes, 0)
+	aa := []c.CacheAccess(nil)
+	gets := []*c.CacheGet(nil)
+	for i := 0; i < 30; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		gets = append(gets, &c.CacheGet{Key: k})
+		aa = append(aa, &c.CacheSet{Key: k, Value: c.CacheValue(k)}, gets[i])
+	}
+	for _, err := range p.AccessSlice(aa) {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	for _, a := range gets {
+		if string(a.Value) != string(a.Key) {
+			t.Error(a.Key, a.Value)
+		}
+	}
+	for _, n := range nodes {
+		if calls := n.CacheProvider.(*TestingCache).Calls; calls != 1 {
+			t.Error(n.Name, calls)
+		}
+	}
+}
+
+func TestSetNodes_Remapping(t *testing.T) {
+	p := New(TestingNodes("a", "b", "c"), 0)
+	keys := make([]c.CacheKey, 1000)
+	for i := range keys {
+		keys[i] = c.CacheKey(fmt.Sprint("key", i))
+	}
+	_, before := p.route(keys)
+	p.SetNodes(append(p.Nodes(), TestingNodes("d")...))
+	_, after := p.route(keys)
+	moved := 0
+	for i := range keys {
+		if before[i] != after[i] {
+			if after[i] != 3 {
+				t.Fatal(keys[i], before[i], after[i])
+			}
+			moved++
+		}
+	}
+	if moved < 150 || moved > 350 {
+		t.Error(moved)
+	}
+}
+
+func TestSetNodes_Weight(t *testing.T) {
+	nodes := TestingNodes("a", "b")
+	nodes[1].Weight = 3
+	p := New(nodes, 0)
+	keys := make([]c.CacheKey, 4000)
+	for i := range keys {
+		keys[i] = c.CacheKey(fmt.Sprint("key", i))
+	}
+	_, indexes := p.route(keys)
+	counts := make([]int, 2)
+	for _, n := range indexes {
+		counts[n]++
+	}
+	if counts[0] < 800 || counts[0] > 1200 {
+		t.Error(counts)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	nodes := TestingNodes("a", "b", "c")
+	p := New(nodes, 0)
+	for i := 0; i < 10; i++ {
+		k := c.CacheKey(fmt.Sprint("key", i))
+		p.Access(c.CacheSet{Key: k, Value: c.CacheValue(k)})
+	}
+	keys := []c.CacheKey(nil)
+	for i := 0; i < 5; i++ {
+		keys = append(keys, c.CacheKey(fmt.Sprint("key", i)))
+	}
+	p.Invalidate(keys...)
+	total := 0
+	for _, n := range nodes {
+		total += n.CacheProvider.(*TestingCache).Statistics().Entries
+	}
+	if total != 5 {
+		t.Error(total)
+	}
+}
@@ -0,0 +1,10 @@
+/*
+Implementation of CacheProvider sharding the entries among other
+CacheProviders.
+
+Cache distributes the CacheKeys among its Nodes with a consistent hashing Ring,
+so changing the Nodes only remaps the keys of the added or removed Nodes.
+AccessSlice splits the CacheAccesses by Node and accesses the Nodes in
+parallel.
+*/
+package heptane
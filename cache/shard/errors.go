@@ -0,0 +1,24 @@
+package heptane
+
+import (
+	"fmt"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// UnsupportedCacheAccessTypeError is produced when the type of a CacheAccess
+// is not supported. Current supported types are CacheGet and CacheSet.
+type UnsupportedCacheAccessTypeError struct {
+	CacheAccess c.CacheAccess
+}
+
+func (e UnsupportedCacheAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported CacheAccess Type: %#v", e.CacheAccess)
+}
+
+// NoNodeError is produced when a Cache has no Nodes.
+type NoNodeError struct{}
+
+func (e NoNodeError) Error() string {
+	return "No Node in Cache"
+}
@@ -0,0 +1,169 @@
+package heptane
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+)
+
+// DefaultTTL is the TTL of the L1 entries when none is given.
+const DefaultTTL = time.Second
+
+// DefaultL1Entries is the maximum number of entries of the L1 created when none
+// is given.
+const DefaultL1Entries = 1024
+
+// Cache implements CacheProvider and CacheInvalidator.
+type Cache struct {
+	// L1 is the first level cache. A nil L1 means an in-process cache of
+	// DefaultL1Entries entries.
+	L1 c.CacheProvider
+	// L2 is the second level cache.
+	L2 c.CacheProvider
+	// TTL is the time the entries stay in L1. Zero means DefaultTTL.
+	TTL time.Duration
+	// Invalidator, if not nil, is called with the CacheKeys of the CacheSets
+	// performed on L2 by each call to Access or AccessSlice, even the failed
+	// ones, so the other instances sharing L2 may Invalidate them in their
+	// L1.
+	Invalidator func([]c.CacheKey)
+
+	once sync.Once
+	now  func() time.Time
+}
+
+func (p *Cache) init() {
+	p.once.Do(func() {
+		if p.L1 == nil {
+			p.L1 = &cm.Cache{MaxEntries: DefaultL1Entries}
+		}
+		if p.now == nil {
+			p.now = time.Now
+		}
+	})
+}
+
+// encode returns the L1 value of an entry: its expiration time followed by its
+// value.
+func (p *Cache) encode(v c.CacheValue) c.CacheValue {
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	e := make(c.CacheValue, 8, 8+len(v))
+	binary.BigEndian.PutUint64(e, uint64(p.now().Add(ttl).UnixNano()))
+	return append(e, v...)
+}
+
+// decode returns the value of an L1 entry, or nil if it expired.
+func (p *Cache) decode(e c.CacheValue) c.CacheValue {
+	if len(e) < 8 || int64(binary.BigEndian.Uint64(e)) <= p.now().UnixNano() {
+		return nil
+	}
+	return append(c.CacheValue{}, e[8:]...)
+}
+
+// Invalidate implements CacheInvalidator. It removes the entries with the
+// given CacheKeys from L1.
+func (p *Cache) Invalidate(keys ...c.CacheKey) {
+	p.init()
+	aa := []c.CacheAccess(nil)
+	for _, k := range keys {
+		aa = append(aa, c.CacheSet{Key: k})
+	}
+	p.L1.AccessSlice(aa)
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The CacheGets are read from L1 in a
+// single AccessSlice, and the misses and the CacheSets are performed on L2 in
+// another one. Errors of L1 are handled as misses.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	p.init()
+	errs := make([]error, len(aa))
+	// CacheGets following a CacheSet of the same key in aa are read from
+	// L2, so they see the new value.
+	set := map[c.CacheKey]bool{}
+	l1 := []c.CacheAccess(nil)
+	l1Indexes := []int(nil)
+	for i, a := range aa {
+		switch a := a.(type) {
+		case *c.CacheGet:
+			if !set[a.Key] {
+				l1 = append(l1, &c.CacheGet{Key: a.Key})
+				l1Indexes = append(l1Indexes, i)
+			}
+		case c.CacheSet:
+			set[a.Key] = true
+		case *c.CacheSet:
+			set[a.Key] = true
+		default:
+			errs[i] = UnsupportedCacheAccessTypeError{a}
+		}
+	}
+	hit := make([]bool, len(aa))
+	if l1 != nil {
+		for j, err := range p.L1.AccessSlice(l1) {
+			if err != nil {
+				continue
+			}
+			if v := p.decode(l1[j].(*c.CacheGet).Value); v != nil {
+				i := l1Indexes[j]
+				aa[i].(*c.CacheGet).Value = v
+				hit[i] = true
+			}
+		}
+	}
+	l2 := []c.CacheAccess(nil)
+	l2Indexes := []int(nil)
+	for i, a := range aa {
+		if errs[i] == nil && !hit[i] {
+			l2 = append(l2, a)
+			l2Indexes = append(l2Indexes, i)
+		}
+	}
+	if l2 == nil {
+		return errs
+	}
+	l2Errs := p.L2.AccessSlice(l2)
+	l1 = nil
+	keys := []c.CacheKey(nil)
+	for j, a := range l2 {
+		errs[l2Indexes[j]] = l2Errs[j]
+		switch a := a.(type) {
+		case *c.CacheGet:
+			if l2Errs[j] == nil && a.Value != nil {
+				l1 = append(l1, c.CacheSet{Key: a.Key, Value: p.encode(a.Value)})
+			}
+		case c.CacheSet:
+			l1 = append(l1, p.written(a, l2Errs[j]))
+			keys = append(keys, a.Key)
+		case *c.CacheSet:
+			l1 = append(l1, p.written(*a, l2Errs[j]))
+			keys = append(keys, a.Key)
+		}
+	}
+	if l1 != nil {
+		p.L1.AccessSlice(l1)
+	}
+	if keys != nil && p.Invalidator != nil {
+		p.Invalidator(keys)
+	}
+	return errs
+}
+
+// written returns the CacheSet of L1 after a CacheSet of L2. The entry is
+// removed from L1 if the CacheSet of L2 failed, as L2 may have changed anyway.
+func (p *Cache) written(a c.CacheSet, err error) c.CacheSet {
+	if err != nil || a.Value == nil {
+		return c.CacheSet{Key: a.Key}
+	}
+	return c.CacheSet{Key: a.Key, Value: p.encode(a.Value)}
+}
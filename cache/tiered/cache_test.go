@@ -0,0 +1,197 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+)
+
+// TestingCache is a CacheProvider recording the accesses to an in-process
+// cache and failing the CacheSets of the Failing key.
+type TestingCache struct {
+	cm.Cache
+	Failing  c.CacheKey
+	Accesses []string
+}
+
+func (p *TestingCache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+func (p *TestingCache) AccessSlice(aa []c.CacheAccess) []error {
+	errs := make([]error, len(aa))
+	for i, a := range aa {
+		if b, ok := a.(*c.CacheSet); ok {
+			a = *b
+		}
+		switch a := a.(type) {
+		case *c.CacheGet:
+			p.Accesses = append(p.Accesses, fmt.Sprint("get ", a.Key))
+		case c.CacheSet:
+			p.Accesses = append(p.Accesses, fmt.Sprint("set ", a.Key))
+			if a.Key == p.Failing {
+				errs[i] = errors.New("failing")
+				continue
+			}
+		}
+		errs[i] = p.Cache.Access(a)
+	}
+	return errs
+}
+
+func TestingTiered() (*Cache, *TestingCache, *time.Time) {
+	now := time.Unix(1000, 0)
+	l2 := &TestingCache{}
+	p := &Cache{L2: l2, now: func() time.Time { return now }}
+	return p, l2, &now
+}
+
+func TestAccess_ReadThrough(t *testing.T) {
+	p, l2, now := TestingTiered()
+	l2.Cache.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")})
+	for i := 0; i < 3; i++ {
+		a := &c.CacheGet{Key: "foo"}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if string(a.Value) != "bar" {
+			t.Error(a.Value)
+		}
+	}
+	if s := fmt.Sprint(l2.Accesses); s != `[get foo]` {
+		t.Error(s)
+	}
+	// The L1 entry expires after the TTL.
+	*now = now.Add(DefaultTTL)
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprint(l2.Accesses); s != `[get foo get foo]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_Miss(t *testing.T) {
+	p, l2, _ := TestingTiered()
+	for i := 0; i < 2; i++ {
+		a := &c.CacheGet{Key: "foo"}
+		if err := p.Access(a); err != nil {
+			t.Error(err)
+		} else if a.Value != nil {
+			t.Error(a.Value)
+		}
+	}
+	// Misses are not kept in L1.
+	if s := fmt.Sprint(l2.Accesses); s != `[get foo get foo]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_WriteThrough(t *testing.T) {
+	p, l2, _ := TestingTiered()
+	invalidated := []c.CacheKey(nil)
+	p.Invalidator = func(keys []c.CacheKey) { invalidated = append(invalidated, keys...) }
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	if err := p.Access(&c.CacheSet{Key: "foo", Value: c.CacheValue{}}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if a.Value == nil || len(a.Value) != 0 {
+		t.Error(a.Value)
+	}
+	if err := p.Access(c.CacheSet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if a.Value != nil {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprint(l2.Accesses); s != `[set foo set foo set foo get foo]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprint(invalidated); s != `[foo foo foo]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_WriteError(t *testing.T) {
+	p, l2, _ := TestingTiered()
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")}); err != nil {
+		t.Error(err)
+	}
+	l2.Failing = "foo"
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("baz")}); err == nil {
+		t.Error(err)
+	}
+	// The L1 entry is removed, so the next CacheGet reads L2.
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprint(l2.Accesses); s != `[set foo set foo get foo]` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice(t *testing.T) {
+	p, l2, _ := TestingTiered()
+	l2.Cache.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("1")})
+	l2.Cache.Access(c.CacheSet{Key: "bar", Value: c.CacheValue("2")})
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	l2.Accesses = nil
+	gets := []*c.CacheGet{{Key: "foo"}, {Key: "bar"}, {Key: "foo"}}
+	errs := p.AccessSlice([]c.CacheAccess{gets[0], gets[1], c.CacheSet{Key: "foo", Value: c.CacheValue("3")}, gets[2], c.CacheGet{}})
+	if s := fmt.Sprint(errs[:4]); s != `[<nil> <nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	if _, ok := errs[4].(UnsupportedCacheAccessTypeError); !ok {
+		t.Error(errs[4])
+	}
+	if s := fmt.Sprintf("%s %s %s", gets[0].Value, gets[1].Value, gets[2].Value); s != `1 2 3` {
+		t.Error(s)
+	}
+	// The CacheGet following the CacheSet of the same key is read from L2.
+	if s := fmt.Sprint(l2.Accesses); s != `[get bar set foo get foo]` {
+		t.Error(s)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	p, l2, _ := TestingTiered()
+	l2.Cache.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")})
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	// Another instance changes L2.
+	l2.Cache.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("baz")})
+	p.Invalidate("foo")
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "baz" {
+		t.Error(a.Value)
+	}
+	if s := fmt.Sprint(l2.Accesses); s != `[get foo get foo]` {
+		t.Error(s)
+	}
+}
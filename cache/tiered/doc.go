@@ -0,0 +1,10 @@
+/*
+Implementation of CacheProvider layering two CacheProviders.
+
+Cache keeps a small cache, usually in-process, in front of a bigger one, usually
+remote. CacheGets are read from L1 and read through to L2 on a miss. CacheSets
+are written through to L2 and then to L1. The L1 entries expire after a short
+TTL, and may be invalidated before by other instances sharing the same L2
+through the Invalidate method.
+*/
+package heptane
@@ -0,0 +1,17 @@
+package heptane
+
+import (
+	"fmt"
+
+	c "github.com/heptanes/heptane/cache"
+)
+
+// UnsupportedCacheAccessTypeError is produced when the type of a CacheAccess
+// is not supported. Current supported types are CacheGet and CacheSet.
+type UnsupportedCacheAccessTypeError struct {
+	CacheAccess c.CacheAccess
+}
+
+func (e UnsupportedCacheAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported CacheAccess Type: %#v", e.CacheAccess)
+}
@@ -2,7 +2,7 @@
 HEPTANE - cacHEd disPerse Table bAckeNd framEwork.
 
 This package provides a main interface Heptane and one implementation
-accessible from the constructor New().
+accessible from the constructors New() and NewWithOptions().
 
 Heptane maintains metadata about a set of tables (an instance of Table, an
 instance of RowProvider and an optional instance of CacheProvider per table)
@@ -66,8 +66,8 @@ RowProvider and, if successful, sends a CacheSet operation to the
 CacheProvider.
 
 When the full Values are not given, Update sends the partial RowUpdate
-operation to the RowProvider and, if successful, sends a RowRetrieve to the
-RowProvider and, if successful, it sends a CacheSet operation to the
+operation to the RowProvider and, if successful, sends a Consistent RowRetrieve
+to the RowProvider and, if successful, it sends a CacheSet operation to the
 CacheProvider.
 
 Delete sends a RowDelete operation to the RowProvider and, if successful, sends
@@ -77,5 +77,39 @@ the RowProvider.
 
 Retrieve must be passed as reference so the RetrievedValues set by Heptane may
 be read by the client code.
+
+Invalidations
+
+When several instances of an application keep local copies of the cache
+entries, a Bus may be given in the Options of NewWithOptions. After the
+CacheSet of each Create, Update and Delete, an Invalidation with the TableName
+and the cache key is published through the Bus, and the Invalidations received
+from the other instances evict the cache key from the CacheProvider of the
+table, if it implements CacheInvalidator. Close unsubscribes the instance from
+the Bus.
+
+Cache Failures
+
+By default an error of a CacheProvider is returned as an error of the Access.
+The CachePolicy of the Options, which may be overridden for each table with
+CachePolicies, changes that. With CacheFallback a failed CacheGet is handled as
+a miss and the row is read from the RowProvider, and a failed CacheSet after a
+written row returns a StaleCacheError, because the row was written but the
+cache entry may be stale. With CacheIgnore every error of the CacheProvider is
+tolerated. The tolerated errors are reported to CacheErrors, if it is given.
+
+Timeouts
+
+The Timeouts of the Options, which may be overridden for each table with
+TableTimeouts, bound the time of every access to the CacheProvider and to the
+RowProvider of an Access. An Access wrapped in a Timed has its own Timeouts
+and an optional Deadline. A phase that exceeds its bound produces a
+TimeoutError naming the Phase, like CacheGet, RowRetrieve or CacheSet, wrapped
+in a CacheProviderAccessError, handled by the CachePolicy, or in a
+RowProviderAccessError. The providers are not interrupted, so a phase that
+timed out may still be completed later. The CacheSets of a cache entry are
+performed in order, so a late CacheSet never overwrites a later one, and when
+the RowAccess of a Create, Update or Delete times out the cache entry is
+deleted, because the row may still be written.
 */
 package heptane
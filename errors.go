@@ -2,7 +2,9 @@ package heptane
 
 import (
 	"fmt"
+	"time"
 
+	b "github.com/heptanes/heptane/bus"
 	c "github.com/heptanes/heptane/cache"
 	r "github.com/heptanes/heptane/row"
 )
@@ -38,6 +40,10 @@ func (e RowProviderAccessError) Error() string {
 	return fmt.Sprintf("%#v Error: %v", e.Access, e.Err)
 }
 
+func (e RowProviderAccessError) Unwrap() error {
+	return e.Err
+}
+
 // CacheProviderAccessError is produced when a CacheProvider returns an error
 // for a given CacheAccess.
 type CacheProviderAccessError struct {
@@ -49,6 +55,51 @@ func (e CacheProviderAccessError) Error() string {
 	return fmt.Sprintf("%#v Error: %v", e.Access, e.Err)
 }
 
+func (e CacheProviderAccessError) Unwrap() error {
+	return e.Err
+}
+
+// StaleCacheError is produced when a row has been written by the RowProvider
+// but the CacheProvider failed, so the cache may contain a stale row.
+type StaleCacheError struct {
+	TableName r.TableName
+	Err       CacheProviderAccessError
+}
+
+func (e StaleCacheError) Error() string {
+	return fmt.Sprintf("Stale Cache for Table %v: %v", e.TableName, e.Err)
+}
+
+func (e StaleCacheError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is produced when a Phase of an Access on a table exceeds its
+// Timeout.
+type TimeoutError struct {
+	TableName r.TableName
+	Phase     Phase
+	Timeout   time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("Timeout of %v in %v of Table %v", e.Timeout, e.Phase, e.TableName)
+}
+
+// BusPublishError is produced when an Invalidation cannot be published.
+type BusPublishError struct {
+	Invalidation b.Invalidation
+	Err          error
+}
+
+func (e BusPublishError) Error() string {
+	return fmt.Sprintf("%#v Error: %v", e.Invalidation, e.Err)
+}
+
+func (e BusPublishError) Unwrap() error {
+	return e.Err
+}
+
 // UnsupportedAccessTypeError is produced when the type of an Access is not
 // supported. Current supported types are Create, Retrieve, Update and Delete.
 type UnsupportedAccessTypeError struct {
@@ -91,3 +142,7 @@ type MultipleErrors struct {
 func (e MultipleErrors) Error() string {
 	return fmt.Sprintf("Multiple Errors: %v", e.Errors)
 }
+
+func (e MultipleErrors) Unwrap() []error {
+	return e.Errors
+}
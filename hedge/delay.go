@@ -0,0 +1,155 @@
+package heptane
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	rt "github.com/heptanes/heptane/retry"
+)
+
+// Default values of the fields of Delay.
+const (
+	DefaultPercentile   = 0.95
+	DefaultSamples      = 1000
+	DefaultMinSamples   = 10
+	DefaultInitialDelay = 10 * time.Millisecond
+)
+
+// Delay computes the time after which a read is hedged from the latencies of
+// the previous reads. The zero value is a Delay with the default values.
+type Delay struct {
+	// Percentile is the ratio of the latencies lower than the Delay. Zero
+	// means DefaultPercentile.
+	Percentile float64
+	// Samples is the number of recent latencies kept. Zero means
+	// DefaultSamples.
+	Samples int
+	// MinSamples is the number of latencies needed to compute the Delay.
+	// Zero means DefaultMinSamples.
+	MinSamples int
+	// Initial is the Delay until there are MinSamples latencies. Zero means
+	// DefaultInitialDelay.
+	Initial time.Duration
+
+	m         sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// Observe records the latency of a read.
+func (d *Delay) Observe(latency time.Duration) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	n := d.Samples
+	if n <= 0 {
+		n = DefaultSamples
+	}
+	if len(d.latencies) < n {
+		d.latencies = append(d.latencies, latency)
+		return
+	}
+	d.latencies[d.next%len(d.latencies)] = latency
+	d.next++
+}
+
+// Delay returns the time after which a read is hedged.
+func (d *Delay) Delay() time.Duration {
+	d.m.Lock()
+	min := d.MinSamples
+	if min <= 0 {
+		min = DefaultMinSamples
+	}
+	if len(d.latencies) < min {
+		d.m.Unlock()
+		if d.Initial <= 0 {
+			return DefaultInitialDelay
+		}
+		return d.Initial
+	}
+	latencies := append([]time.Duration(nil), d.latencies...)
+	d.m.Unlock()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := d.Percentile
+	if percentile <= 0 || percentile > 1 {
+		percentile = DefaultPercentile
+	}
+	i := int(percentile * float64(len(latencies)))
+	if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i]
+}
+
+type result struct {
+	attempt int
+	errs    []error
+}
+
+func succeeded(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// run performs the first attempt and, if it has not succeeded within the
+// Delay or it has failed with retryable errors only, the second one. It returns
+// the first successful attempt or, if both fail, the first one to finish. Only
+// the latencies of the successful attempts are observed.
+func (d *Delay) run(attempts int, retryable func(error) bool, send func(attempt int) []error) (int, []error) {
+	ch := make(chan result, attempts)
+	launched, received := 0, 0
+	launch := func() {
+		go func(attempt int) {
+			start := time.Now()
+			errs := send(attempt)
+			if succeeded(errs) {
+				d.Observe(time.Since(start))
+			}
+			ch <- result{attempt, errs}
+		}(launched)
+		launched++
+	}
+	launch()
+	timer := time.NewTimer(d.Delay())
+	defer timer.Stop()
+	failed := result{-1, nil}
+	for {
+		select {
+		case <-timer.C:
+			if launched < attempts {
+				launch()
+			}
+		case res := <-ch:
+			received++
+			if succeeded(res.errs) {
+				return res.attempt, res.errs
+			}
+			if failed.attempt < 0 {
+				failed = res
+			}
+			if launched < attempts && hedgeable(res.errs, retryable) {
+				launch()
+			} else if received == launched {
+				return failed.attempt, failed.errs
+			}
+		}
+	}
+}
+
+// hedgeable reports whether every error of a failed attempt is retryable, so
+// another replica may succeed.
+func hedgeable(errs []error, retryable func(error) bool) bool {
+	if retryable == nil {
+		retryable = rt.Retryable
+	}
+	for _, err := range errs {
+		if err != nil && !retryable(err) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package heptane
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	d := &Delay{Samples: 100}
+	if v := d.Delay(); v != DefaultInitialDelay {
+		t.Error(v)
+	}
+	for i := 1; i <= 200; i++ {
+		d.Observe(time.Duration(i) * time.Millisecond)
+	}
+	// Only the latest 100 latencies are kept: 101ms to 200ms.
+	if v := d.Delay(); v != 196*time.Millisecond {
+		t.Error(v)
+	}
+	d.Percentile = 0.5
+	if v := d.Delay(); v != 151*time.Millisecond {
+		t.Error(v)
+	}
+}
@@ -0,0 +1,16 @@
+/*
+Hedged reads for replicated CacheProviders and RowProviders.
+
+Row performs the reads, non Consistent RowRetrieves, in one of its replicas,
+chosen in turn. Cache performs the reads, CacheGets, in its first replica, the
+only one sure to have the last writes. If the replica has not answered within
+the Delay, a percentile of the latencies of the previous successful reads, or
+it has failed with a retryable error, the reads are sent to another replica
+too, and the first successful answer is taken. The accesses lack a context, so
+the losing request cannot be interrupted: it runs to completion on its own
+copies of the accesses and its answer is discarded.
+
+The writes, and any AccessSlice mixing reads and writes, are performed only in
+the first replica, which must replicate them to the others.
+*/
+package heptane
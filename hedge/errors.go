@@ -0,0 +1,8 @@
+package heptane
+
+// NoReplicaError is produced when there is no replica to perform an access.
+type NoReplicaError struct{}
+
+func (e NoReplicaError) Error() string {
+	return "No Replica"
+}
@@ -0,0 +1,162 @@
+package heptane
+
+import (
+	"sync/atomic"
+
+	c "github.com/heptanes/heptane/cache"
+	r "github.com/heptanes/heptane/row"
+)
+
+// replicas returns the number of attempts for n replicas and the replica of
+// each attempt, starting at the next one in turn.
+func replicas(n int, next *uint32) (int, func(int) int) {
+	first := int(atomic.AddUint32(next, 1)-1) % n
+	attempts := 2
+	if n < 2 {
+		attempts = n
+	}
+	return attempts, func(attempt int) int { return (first + attempt) % n }
+}
+
+// primaryFirst returns the number of attempts for n replicas and the replica
+// of each attempt, starting at the first one and hedging to the next one of
+// the others in turn.
+func primaryFirst(n int, next *uint32) (int, func(int) int) {
+	if n < 2 {
+		return n, func(int) int { return 0 }
+	}
+	second := 1 + int(atomic.AddUint32(next, 1)-1)%(n-1)
+	return 2, func(attempt int) int {
+		if attempt == 0 {
+			return 0
+		}
+		return second
+	}
+}
+
+func repeat(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Cache implements CacheProvider, hedging the CacheGets among its replicas.
+// The CacheGets are sent to the first replica, which performs the writes, and
+// hedged to the others, which may lag behind it.
+type Cache struct {
+	// CacheProviders are the replicas. The first one performs the writes.
+	CacheProviders []c.CacheProvider
+	// Delay computes the time after which the CacheGets are hedged.
+	Delay
+	// Retryable decides whether the CacheGets that failed in a replica are
+	// sent to another one. Nil means the function Retryable of retry.
+	Retryable func(error) bool
+
+	next uint32
+}
+
+// Access implements CacheProvider.
+func (p *Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The CacheAccesses are hedged if all of
+// them are CacheGets.
+func (p *Cache) AccessSlice(aa []c.CacheAccess) []error {
+	if len(p.CacheProviders) == 0 {
+		return repeat(NoReplicaError{}, len(aa))
+	}
+	for _, a := range aa {
+		if _, ok := a.(*c.CacheGet); !ok {
+			return p.CacheProviders[0].AccessSlice(aa)
+		}
+	}
+	attempts, replica := primaryFirst(len(p.CacheProviders), &p.next)
+	copies := make([][]c.CacheAccess, attempts)
+	for j := range copies {
+		copies[j] = make([]c.CacheAccess, len(aa))
+		for i, a := range aa {
+			g := *a.(*c.CacheGet)
+			copies[j][i] = &g
+		}
+	}
+	attempt, errs := p.Delay.run(attempts, p.Retryable, func(attempt int) []error {
+		return p.CacheProviders[replica(attempt)].AccessSlice(copies[attempt])
+	})
+	for i, a := range aa {
+		a.(*c.CacheGet).Value = copies[attempt][i].(*c.CacheGet).Value
+	}
+	return errs
+}
+
+// Row implements RowProvider, hedging the non Consistent RowRetrieves among
+// its replicas.
+type Row struct {
+	// RowProviders are the replicas. The first one performs the writes and
+	// the Consistent RowRetrieves.
+	RowProviders []r.RowProvider
+	// Delay computes the time after which the RowRetrieves are hedged.
+	Delay
+	// Retryable decides whether the RowRetrieves that failed in a replica
+	// are sent to another one. Nil means the function Retryable of retry.
+	Retryable func(error) bool
+
+	next uint32
+}
+
+// Access implements RowProvider.
+func (p *Row) Access(a r.RowAccess) error {
+	return p.AccessSlice([]r.RowAccess{a})[0]
+}
+
+// AccessSlice implements RowProvider. The RowAccesses are hedged if all of
+// them are non Consistent RowRetrieves.
+func (p *Row) AccessSlice(aa []r.RowAccess) []error {
+	if len(p.RowProviders) == 0 {
+		return repeat(NoReplicaError{}, len(aa))
+	}
+	for _, a := range aa {
+		if rr, ok := a.(*r.RowRetrieve); !ok || rr.Consistent {
+			return p.RowProviders[0].AccessSlice(aa)
+		}
+	}
+	attempts, replica := replicas(len(p.RowProviders), &p.next)
+	copies := make([][]r.RowAccess, attempts)
+	for j := range copies {
+		copies[j] = make([]r.RowAccess, len(aa))
+		for i, a := range aa {
+			rr := *a.(*r.RowRetrieve)
+			copies[j][i] = &rr
+		}
+	}
+	attempt, errs := p.Delay.run(attempts, p.Retryable, func(attempt int) []error {
+		return p.RowProviders[replica(attempt)].AccessSlice(copies[attempt])
+	})
+	for i, a := range aa {
+		a.(*r.RowRetrieve).RetrievedValues = copies[attempt][i].(*r.RowRetrieve).RetrievedValues
+	}
+	return errs
+}
+
+// VerifyTable implements TableVerifier if the first RowProvider does.
+func (p *Row) VerifyTable(t r.Table) error {
+	if len(p.RowProviders) == 0 {
+		return NoReplicaError{}
+	}
+	if tv, ok := p.RowProviders[0].(r.TableVerifier); ok {
+		return tv.VerifyTable(t)
+	}
+	return nil
+}
+
+// InvalidateTable implements TableInvalidator for every RowProvider that
+// does.
+func (p *Row) InvalidateTable(tn r.TableName) {
+	for _, rp := range p.RowProviders {
+		if ti, ok := rp.(r.TableInvalidator); ok {
+			ti.InvalidateTable(tn)
+		}
+	}
+}
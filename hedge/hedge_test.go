@@ -0,0 +1,193 @@
+package heptane
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+	r "github.com/heptanes/heptane/row"
+)
+
+// TestingCache is an in-process cache answering after Latency, or failing
+// with Err.
+type TestingCache struct {
+	cm.Cache
+	Latency time.Duration
+	Err     error
+
+	m     sync.Mutex
+	calls int
+}
+
+func (p *TestingCache) Calls() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.calls
+}
+
+func (p *TestingCache) AccessSlice(aa []c.CacheAccess) []error {
+	p.m.Lock()
+	p.calls++
+	p.m.Unlock()
+	time.Sleep(p.Latency)
+	if p.Err != nil {
+		return repeat(p.Err, len(aa))
+	}
+	return p.Cache.AccessSlice(aa)
+}
+
+func TestingHedged(latencies ...time.Duration) (*Cache, []*TestingCache) {
+	p := &Cache{Delay: Delay{Initial: 10 * time.Millisecond}}
+	tcs := []*TestingCache(nil)
+	for _, l := range latencies {
+		tc := &TestingCache{Latency: l}
+		tc.Cache.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("bar")})
+		tcs = append(tcs, tc)
+		p.CacheProviders = append(p.CacheProviders, tc)
+	}
+	return p, tcs
+}
+
+func TestCache_Hedged(t *testing.T) {
+	p, tcs := TestingHedged(time.Second, 0)
+	start := time.Now()
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Error(d)
+	}
+	if n := tcs[1].Calls(); n != 1 {
+		t.Error(n)
+	}
+}
+
+func TestCache_NotHedged(t *testing.T) {
+	p, tcs := TestingHedged(0, 0)
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+		t.Error(err)
+	}
+	if n := tcs[0].Calls() + tcs[1].Calls(); n != 1 {
+		t.Error(n)
+	}
+}
+
+// TestingRetryableError is a transient error.
+type TestingRetryableError string
+
+func (e TestingRetryableError) Error() string   { return string(e) }
+func (e TestingRetryableError) Retryable() bool { return true }
+
+func TestCache_Failed(t *testing.T) {
+	p, tcs := TestingHedged(time.Millisecond, 0)
+	tcs[0].Err = TestingRetryableError("problem0")
+	a := &c.CacheGet{Key: "foo"}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if string(a.Value) != "bar" {
+		t.Error(a.Value)
+	}
+	// Both fail, the error of the first replica is the first one.
+	tcs[1].Err = TestingRetryableError("problem1")
+	if err := p.Access(a); err == nil || err.Error() != "problem0" {
+		t.Error(err)
+	}
+	if n := tcs[0].Calls() + tcs[1].Calls(); n != 4 {
+		t.Error(n)
+	}
+	// The failed attempts are not observed.
+	p.Delay.m.Lock()
+	defer p.Delay.m.Unlock()
+	if n := len(p.Delay.latencies); n != 1 {
+		t.Error(n)
+	}
+}
+
+func TestCache_NotRetryable(t *testing.T) {
+	p, tcs := TestingHedged(0, 0)
+	tcs[0].Err = errors.New("problem0")
+	if err := p.Access(&c.CacheGet{Key: "foo"}); err == nil || err.Error() != "problem0" {
+		t.Error(err)
+	}
+	if n := tcs[1].Calls(); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestCache_Primary(t *testing.T) {
+	p, tcs := TestingHedged(time.Second, 0, 0)
+	for i := 0; i < 4; i++ {
+		if err := p.Access(&c.CacheGet{Key: "foo"}); err != nil {
+			t.Error(err)
+		}
+	}
+	// Every CacheGet starts in the first replica and is hedged to the
+	// others in turn.
+	for i, n := range []int{4, 2, 2} {
+		if m := tcs[i].Calls(); m != n {
+			t.Error(i, m)
+		}
+	}
+}
+
+func TestCache_Write(t *testing.T) {
+	p, tcs := TestingHedged(0, 0)
+	if err := p.Access(c.CacheSet{Key: "foo", Value: c.CacheValue("baz")}); err != nil {
+		t.Error(err)
+	}
+	errs := p.AccessSlice([]c.CacheAccess{&c.CacheGet{Key: "foo"}, c.CacheSet{Key: "foo"}})
+	if errs[0] != nil || errs[1] != nil {
+		t.Error(errs)
+	}
+	if n := tcs[1].Calls(); n != 0 {
+		t.Error(n)
+	}
+	if (&Cache{}).Access(&c.CacheGet{}) == nil {
+		t.Error("No Replica")
+	}
+}
+
+// TestingRow is a RowProvider retrieving the Name of its table.
+type TestingRow struct {
+	Name    string
+	Latency time.Duration
+}
+
+func (p *TestingRow) Access(a r.RowAccess) error {
+	return p.AccessSlice([]r.RowAccess{a})[0]
+}
+
+func (p *TestingRow) AccessSlice(aa []r.RowAccess) []error {
+	time.Sleep(p.Latency)
+	for _, a := range aa {
+		if rr, ok := a.(*r.RowRetrieve); ok {
+			rr.RetrievedValues = []r.FieldValuesByName{{"name": p.Name}}
+		}
+	}
+	return make([]error, len(aa))
+}
+
+func TestRow(t *testing.T) {
+	p := &Row{RowProviders: []r.RowProvider{&TestingRow{"primary", time.Second}, &TestingRow{"replica", 0}}}
+	rr := &r.RowRetrieve{}
+	if err := p.Access(rr); err != nil {
+		t.Error(err)
+	} else if v := rr.RetrievedValues[0]["name"]; v != "replica" {
+		t.Error(v)
+	}
+	p = &Row{RowProviders: []r.RowProvider{&TestingRow{"primary", 0}, &TestingRow{"replica", 0}}}
+	rr.Consistent = true
+	for i := 0; i < 2; i++ {
+		if err := p.Access(rr); err != nil {
+			t.Error(err)
+		} else if v := rr.RetrievedValues[0]["name"]; v != "primary" {
+			t.Error(v)
+		}
+	}
+}
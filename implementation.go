@@ -1,8 +1,12 @@
 package heptane
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"sync"
+	"time"
 
+	b "github.com/heptanes/heptane/bus"
 	c "github.com/heptanes/heptane/cache"
 	r "github.com/heptanes/heptane/row"
 )
@@ -13,17 +17,256 @@ type info struct {
 	c.CacheProvider
 }
 
+// entry identifies a cache entry of a table.
+type entry struct {
+	tn  r.TableName
+	key c.CacheKey
+}
+
 type heptane struct {
-	m sync.Mutex
-	f map[r.TableName]*info
+	m       sync.Mutex
+	f       map[r.TableName]*info
+	options Options
+	origin  string
+	pm      sync.Mutex
+	pending map[entry]chan struct{}
+	// unsubscribe unregisters the instance from the Bus, nil if there is
+	// no Bus or it is closed.
+	unsubscribe func()
 }
 
 // New returns a new instance of Heptane.
 func New() Heptane {
-	return &heptane{
-		sync.Mutex{},
-		map[r.TableName]*info{},
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions returns a new instance of Heptane with the given Options. If
+// there is a Bus, the instance subscribes to it until it is closed.
+func NewWithOptions(o Options) Heptane {
+	h := &heptane{
+		f:       map[r.TableName]*info{},
+		options: o,
+		pending: map[entry]chan struct{}{},
+	}
+	if o.Bus != nil {
+		q := make([]byte, 8)
+		rand.Read(q)
+		h.origin = hex.EncodeToString(q)
+		h.unsubscribe = o.Bus.Subscribe(h.invalidate)
+	}
+	return h
+}
+
+func (h *heptane) Close() error {
+	h.m.Lock()
+	unsubscribe := h.unsubscribe
+	h.unsubscribe = nil
+	h.m.Unlock()
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	return nil
+}
+
+// invalidate evicts the local copies of the cache entry written by another
+// instance.
+func (h *heptane) invalidate(i b.Invalidation) {
+	if i.Origin == h.origin {
+		return
 	}
+	f := h.info(i.TableName)
+	if f == nil {
+		return
+	}
+	if ci, ok := f.CacheProvider.(c.CacheInvalidator); ok {
+		ci.Invalidate(i.Key)
+	}
+}
+
+// cacheError handles the error of the CacheProvider of a table according to
+// its CachePolicy. A written row means the error comes from the CacheSet after
+// a Create, Update or Delete.
+func (h *heptane) cacheError(tn r.TableName, err CacheProviderAccessError, written bool) error {
+	policy, ok := h.options.CachePolicies[tn]
+	if !ok {
+		policy = h.options.CachePolicy
+	}
+	if policy == CacheFail {
+		return err
+	}
+	if h.options.CacheErrors != nil {
+		h.options.CacheErrors(err)
+	}
+	if policy == CacheFallback && written {
+		return StaleCacheError{tn, err}
+	}
+	return nil
+}
+
+// deadline contains the bounds of an Access given by Timed.
+type deadline struct {
+	timeouts *Timeouts
+	at       time.Time
+}
+
+// bound returns the bound of the given Phase of an Access on a table, zero
+// meaning no bound, or false if the Deadline has expired.
+func (h *heptane) bound(tn r.TableName, dl deadline, phase Phase) (time.Duration, bool) {
+	t := dl.timeouts
+	if t == nil {
+		if tt, ok := h.options.TableTimeouts[tn]; ok {
+			t = &tt
+		} else {
+			t = &h.options.Timeouts
+		}
+	}
+	d := t.Row
+	if phase == CacheGetPhase || phase == CacheSetPhase {
+		d = t.Cache
+	}
+	if !dl.at.IsZero() {
+		if left := time.Until(dl.at); d == 0 || left < d {
+			d = left
+		}
+		if d <= 0 {
+			return 0, false
+		}
+	}
+	return d, true
+}
+
+// within performs the given Phase of an Access on a table, returning a
+// TimeoutError if it exceeds its bounds. The Phase is not interrupted, its
+// result is discarded.
+func (h *heptane) within(tn r.TableName, dl deadline, phase Phase, f func() error) error {
+	d, ok := h.bound(tn, dl, phase)
+	if !ok {
+		return TimeoutError{tn, phase, 0}
+	}
+	return wait(tn, phase, d, f)
+}
+
+// wait performs f, returning a TimeoutError if it takes longer than d. Zero
+// means no bound.
+func wait(tn r.TableName, phase Phase, d time.Duration, f func() error) error {
+	if d == 0 {
+		return f()
+	}
+	ch := make(chan error, 1)
+	go func() {
+		ch <- f()
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case err := <-ch:
+		return err
+	case <-timer.C:
+		return TimeoutError{tn, phase, d}
+	}
+}
+
+// ordered returns a function performing f after every previous function
+// returned by ordered for the same cache entries of a table, in the order of
+// the calls to ordered, even if they were abandoned by a timeout.
+func (h *heptane) ordered(tn r.TableName, keys []c.CacheKey, f func() error) func() error {
+	done := make(chan struct{})
+	prevs := []chan struct{}(nil)
+	h.pm.Lock()
+	for _, k := range keys {
+		ck := entry{tn, k}
+		if prev := h.pending[ck]; prev != nil && prev != done {
+			prevs = append(prevs, prev)
+		}
+		h.pending[ck] = done
+	}
+	h.pm.Unlock()
+	return func() error {
+		defer func() {
+			h.pm.Lock()
+			for _, k := range keys {
+				if ck := (entry{tn, k}); h.pending[ck] == done {
+					delete(h.pending, ck)
+				}
+			}
+			h.pm.Unlock()
+			close(done)
+		}()
+		for _, prev := range prevs {
+			<-prev
+		}
+		return f()
+	}
+}
+
+// cacheSets performs the CacheSets of a table bounded by the cache timeout.
+// They are performed after the previous CacheSets of the same keys, so a
+// CacheSet abandoned by a timeout never overwrites a later one.
+func (h *heptane) cacheSets(f *info, dl deadline, css []c.CacheAccess) []error {
+	tn := f.Table.Name
+	keys := make([]c.CacheKey, len(css))
+	for i, cs := range css {
+		keys[i] = cs.(c.CacheSet).Key
+	}
+	d, ok := h.bound(tn, dl, CacheSetPhase)
+	// The errors are returned as MultipleErrors, so an AccessSlice that
+	// times out does not write them later.
+	run := h.ordered(tn, keys, func() error {
+		if !ok {
+			return nil
+		}
+		return MultipleErrors{f.CacheProvider.AccessSlice(css)}
+	})
+	errs := make([]error, len(css))
+	err := error(nil)
+	if !ok {
+		// The expired CacheSets are skipped, but the following ones
+		// must still wait for the previous ones.
+		go run()
+		err = TimeoutError{tn, CacheSetPhase, 0}
+	} else {
+		err = wait(tn, CacheSetPhase, d, run)
+	}
+	if me, ok := err.(MultipleErrors); ok {
+		return me.Errors
+	}
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// unwritten handles the error of the RowAccess writing the row with the given
+// cache key. If it timed out, the row may still be written, so the cache entry
+// is deleted, bounded by the cache timeout only.
+func (h *heptane) unwritten(f *info, dl deadline, key c.CacheKey, err RowProviderAccessError) error {
+	if _, ok := err.Err.(TimeoutError); !ok || f.CacheProvider == nil || f.Table.PrimaryKeyCachePrefix == nil {
+		return err
+	}
+	if werr := h.written(f, deadline{timeouts: dl.timeouts}, c.CacheSet{Key: key}); werr != nil {
+		return MultipleErrors{[]error{err, werr}}
+	}
+	return err
+}
+
+// written sends the CacheSet of a written row to the CacheProvider and
+// publishes its Invalidation, even if the CacheSet fails. If both fail, both
+// errors are returned.
+func (h *heptane) written(f *info, dl deadline, cs c.CacheSet) error {
+	err := h.cacheSets(f, dl, []c.CacheAccess{cs})[0]
+	if err != nil {
+		err = h.cacheError(f.Table.Name, CacheProviderAccessError{cs, err}, true)
+	}
+	if h.options.Bus != nil {
+		i := b.Invalidation{Origin: h.origin, TableName: f.Table.Name, Key: cs.Key}
+		if perr := h.options.Bus.Publish(i); perr != nil {
+			if err != nil {
+				return MultipleErrors{[]error{err, BusPublishError{i, perr}}}
+			}
+			return BusPublishError{i, perr}
+		}
+	}
+	return err
 }
 
 func (h *heptane) Register(t r.Table, rp r.RowProvider, cp c.CacheProvider) error {
@@ -33,8 +276,17 @@ func (h *heptane) Register(t r.Table, rp r.RowProvider, cp c.CacheProvider) erro
 	if rp == nil {
 		return NullRowProviderError{t.Name}
 	}
+	if tv, ok := rp.(r.TableVerifier); ok {
+		if err := tv.VerifyTable(t); err != nil {
+			return err
+		}
+	}
 	h.m.Lock()
 	defer h.m.Unlock()
+	if f := h.f[t.Name]; f != nil {
+		invalidateTable(f.RowProvider, t.Name)
+	}
+	invalidateTable(rp, t.Name)
 	h.f[t.Name] = &info{t, rp, cp}
 	return nil
 }
@@ -42,9 +294,18 @@ func (h *heptane) Register(t r.Table, rp r.RowProvider, cp c.CacheProvider) erro
 func (h *heptane) Unregister(tn r.TableName) {
 	h.m.Lock()
 	defer h.m.Unlock()
+	if f := h.f[tn]; f != nil {
+		invalidateTable(f.RowProvider, tn)
+	}
 	delete(h.f, tn)
 }
 
+func invalidateTable(rp r.RowProvider, tn r.TableName) {
+	if ti, ok := rp.(r.TableInvalidator); ok {
+		ti.InvalidateTable(tn)
+	}
+}
+
 func (h *heptane) TableNames() (tns []r.TableName) {
 	h.m.Lock()
 	defer h.m.Unlock()
@@ -81,7 +342,7 @@ func (h *heptane) CacheProvider(tn r.TableName) (cp c.CacheProvider) {
 	return
 }
 
-func (h *heptane) create(a Create) error {
+func (h *heptane) create(a Create, dl deadline) error {
 	tn := a.TableName
 	f := h.info(tn)
 	if f == nil {
@@ -96,20 +357,19 @@ func (h *heptane) create(a Create) error {
 		return err
 	}
 	rc := r.RowCreate{Table: f.Table, FieldValues: a.FieldValues}
-	if err := f.RowProvider.Access(rc); err != nil {
-		return RowProviderAccessError{rc, err}
+	if err := h.within(tn, dl, RowCreatePhase, func() error {
+		return f.RowProvider.Access(rc)
+	}); err != nil {
+		return h.unwritten(f, dl, key.key(), RowProviderAccessError{rc, err})
 	}
 	if f.CacheProvider == nil || f.Table.PrimaryKeyCachePrefix == nil {
 		return nil
 	}
 	cs := c.CacheSet{Key: key.key(), Value: value.value()}
-	if err := f.CacheProvider.Access(cs); err != nil {
-		return CacheProviderAccessError{cs, err}
-	}
-	return nil
+	return h.written(f, dl, cs)
 }
 
-func (h *heptane) retrieve(a *Retrieve) error {
+func (h *heptane) retrieve(a *Retrieve, dl deadline) error {
 	tn := a.TableName
 	f := h.info(tn)
 	if f == nil {
@@ -127,24 +387,37 @@ func (h *heptane) retrieve(a *Retrieve) error {
 		}
 	}
 	if f.CacheProvider != nil && f.Table.PrimaryKeyCachePrefix != nil && err == nil {
+		// The CacheGet is performed on a copy, which is abandoned if it
+		// times out.
 		cg := c.CacheGet{Key: key.key()}
-		if err := f.CacheProvider.Access(&cg); err != nil {
-			return CacheProviderAccessError{cg, err}
-		}
-		cv := split(cg.Value)
-		v, err := encode(f.Table, a.FieldValues, cv)
-		if err != nil {
-			return err
-		}
-		if v != nil {
-			a.RetrievedValues = []r.FieldValuesByName{v}
-			return nil
+		q := cg
+		if err := h.within(tn, dl, CacheGetPhase, func() error {
+			return f.CacheProvider.Access(&q)
+		}); err != nil {
+			// A tolerated error is handled as a miss.
+			if err := h.cacheError(tn, CacheProviderAccessError{cg, err}, false); err != nil {
+				return err
+			}
+		} else {
+			cv := split(q.Value)
+			v, err := encode(f.Table, a.FieldValues, cv)
+			if err != nil {
+				return err
+			}
+			if v != nil {
+				a.RetrievedValues = []r.FieldValuesByName{v}
+				return nil
+			}
 		}
 	}
 	rr := r.RowRetrieve{Table: f.Table, FieldValues: a.FieldValues}
-	if err := f.RowProvider.Access(&rr); err != nil {
+	q := rr
+	if err := h.within(tn, dl, RowRetrievePhase, func() error {
+		return f.RowProvider.Access(&q)
+	}); err != nil {
 		return RowProviderAccessError{rr, err}
 	}
+	rr = q
 	a.RetrievedValues = rr.RetrievedValues
 	if f.CacheProvider != nil && f.Table.PrimaryKeyCachePrefix != nil {
 		css := make([]c.CacheAccess, 0, len(rr.RetrievedValues))
@@ -160,14 +433,17 @@ func (h *heptane) retrieve(a *Retrieve) error {
 			cs := c.CacheSet{Key: key.key(), Value: value.value()}
 			css = append(css, cs)
 		}
-		errs := f.CacheProvider.AccessSlice(css)
+		errs := h.cacheSets(f, dl, css)
 		nnerrs := []error(nil)
 		for i, err := range errs {
-			if err != nil {
+			if err == nil {
+				continue
+			}
+			if err := h.cacheError(tn, CacheProviderAccessError{css[i], err}, false); err != nil {
 				if nnerrs == nil {
 					nnerrs = make([]error, 0, len(errs))
 				}
-				nnerrs = append(nnerrs, CacheProviderAccessError{css[i], err})
+				nnerrs = append(nnerrs, err)
 			}
 		}
 		if len(nnerrs) > 0 {
@@ -180,7 +456,7 @@ func (h *heptane) retrieve(a *Retrieve) error {
 	return nil
 }
 
-func (h *heptane) update(a Update) error {
+func (h *heptane) update(a Update, dl deadline) error {
 	tn := a.TableName
 	f := h.info(tn)
 	if f == nil {
@@ -194,8 +470,10 @@ func (h *heptane) update(a Update) error {
 		return err
 	}
 	ru := r.RowUpdate{Table: f.Table, FieldValues: a.FieldValues}
-	if err := f.RowProvider.Access(ru); err != nil {
-		return RowProviderAccessError{ru, err}
+	if err := h.within(tn, dl, RowUpdatePhase, func() error {
+		return f.RowProvider.Access(ru)
+	}); err != nil {
+		return h.unwritten(f, dl, key.key(), RowProviderAccessError{ru, err})
 	}
 	if f.CacheProvider == nil || f.Table.PrimaryKeyCachePrefix == nil {
 		return nil
@@ -206,24 +484,24 @@ func (h *heptane) update(a Update) error {
 		for _, fn := range f.Table.PrimaryKey {
 			kv[fn] = a.FieldValues[fn]
 		}
-		rr := r.RowRetrieve{Table: f.Table, FieldValues: kv}
-		if err := f.RowProvider.Access(&rr); err != nil {
+		rr := r.RowRetrieve{Table: f.Table, FieldValues: kv, Consistent: true}
+		q := rr
+		if err := h.within(tn, dl, RowRetrievePhase, func() error {
+			return f.RowProvider.Access(&q)
+		}); err != nil {
 			return RowProviderAccessError{rr, err}
 		}
-		fv = rr.RetrievedValues[0]
+		fv = q.RetrievedValues[0]
 	}
 	value, err := decodeValue(f.Table, fv)
 	if err != nil {
 		return err
 	}
 	cs := c.CacheSet{Key: key.key(), Value: value.value()}
-	if err := f.CacheProvider.Access(cs); err != nil {
-		return CacheProviderAccessError{cs, err}
-	}
-	return nil
+	return h.written(f, dl, cs)
 }
 
-func (h *heptane) delete(a Delete) error {
+func (h *heptane) delete(a Delete, dl deadline) error {
 	tn := a.TableName
 	f := h.info(tn)
 	if f == nil {
@@ -234,36 +512,43 @@ func (h *heptane) delete(a Delete) error {
 		return err
 	}
 	rd := r.RowDelete{Table: f.Table, FieldValues: a.FieldValues}
-	if err := f.RowProvider.Access(rd); err != nil {
-		return RowProviderAccessError{rd, err}
+	if err := h.within(tn, dl, RowDeletePhase, func() error {
+		return f.RowProvider.Access(rd)
+	}); err != nil {
+		return h.unwritten(f, dl, key.key(), RowProviderAccessError{rd, err})
 	}
 	if f.CacheProvider == nil || f.Table.PrimaryKeyCachePrefix == nil {
 		return nil
 	}
 	value := cacheValue(nil)
 	cs := c.CacheSet{Key: key.key(), Value: value.value()}
-	if err := f.CacheProvider.Access(cs); err != nil {
-		return CacheProviderAccessError{cs, err}
-	}
-	return nil
+	return h.written(f, dl, cs)
 }
 
 func (h *heptane) Access(a Access) error {
+	return h.access(a, deadline{})
+}
+
+func (h *heptane) access(a Access, dl deadline) error {
 	switch a := a.(type) {
 	case Create:
-		return h.create(a)
+		return h.create(a, dl)
 	case *Create:
-		return h.create(*a)
+		return h.create(*a, dl)
 	case *Retrieve:
-		return h.retrieve(a)
+		return h.retrieve(a, dl)
 	case Update:
-		return h.update(a)
+		return h.update(a, dl)
 	case *Update:
-		return h.update(*a)
+		return h.update(*a, dl)
 	case Delete:
-		return h.delete(a)
+		return h.delete(a, dl)
 	case *Delete:
-		return h.delete(*a)
+		return h.delete(*a, dl)
+	case Timed:
+		return h.access(a.Access, deadline{a.Timeouts, a.Deadline})
+	case *Timed:
+		return h.access(a.Access, deadline{a.Timeouts, a.Deadline})
 	}
 	return UnsupportedAccessTypeError{a}
 }
@@ -0,0 +1,109 @@
+package heptane
+
+import (
+	"errors"
+	"testing"
+
+	b "github.com/heptanes/heptane/bus"
+	bl "github.com/heptanes/heptane/bus/local"
+	cm "github.com/heptanes/heptane/cache/memory"
+	r "github.com/heptanes/heptane/row"
+	rm "github.com/heptanes/heptane/row/mock"
+)
+
+type TestingFailingBus struct{}
+
+func (TestingFailingBus) Publish(b.Invalidation) error {
+	return errors.New("bogus")
+}
+
+func (TestingFailingBus) Subscribe(func(b.Invalidation)) func() {
+	return func() {}
+}
+
+func TestHeptane_Bus(t *testing.T) {
+	bus := &bl.Bus{}
+	tb := TestingTable1()
+	fvn := r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}
+	rp := &rm.Row{}
+	rp.Mock(r.RowCreate{Table: tb, FieldValues: fvn}, nil)
+	rp.Mock(r.RowDelete{Table: tb, FieldValues: fvn}, nil)
+	hs := []Heptane{NewWithOptions(Options{Bus: bus}), NewWithOptions(Options{Bus: bus})}
+	cps := []*cm.Cache{{}, {}}
+	for i, h := range hs {
+		if err := h.Register(tb, rp, cps[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hs[0].Access(Create{tb.Name, fvn}); err != nil {
+		t.Error(err)
+	}
+	if err := hs[1].Access(Create{tb.Name, fvn}); err != nil {
+		t.Error(err)
+	}
+	// The entry written by the second instance is evicted from the cache of
+	// the first one.
+	if s := cps[0].Statistics(); s.Entries != 0 {
+		t.Error(s)
+	}
+	if s := cps[1].Statistics(); s.Entries != 1 {
+		t.Error(s)
+	}
+	// The entry deleted by the first instance is evicted from the cache of
+	// the second one.
+	if err := hs[0].Access(Delete{tb.Name, fvn}); err != nil {
+		t.Error(err)
+	}
+	if s := cps[1].Statistics(); s.Entries != 0 {
+		t.Error(s)
+	}
+}
+
+func TestHeptane_Bus_PublishError(t *testing.T) {
+	tb := TestingTable1()
+	fvn := r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}
+	rp := &rm.Row{}
+	rp.Mock(r.RowCreate{Table: tb, FieldValues: fvn}, nil)
+	h := NewWithOptions(Options{Bus: TestingFailingBus{}})
+	if err := h.Register(tb, rp, &cm.Cache{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Access(Create{tb.Name, fvn}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(BusPublishError); !ok {
+		t.Error(err)
+	}
+}
+
+func TestHeptane_Bus_Close(t *testing.T) {
+	bus := &bl.Bus{}
+	tb := TestingTable1()
+	fvn := r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}
+	rp := &rm.Row{}
+	rp.Mock(r.RowCreate{Table: tb, FieldValues: fvn}, nil)
+	hs := []Heptane{NewWithOptions(Options{Bus: bus}), NewWithOptions(Options{Bus: bus})}
+	cps := []*cm.Cache{{}, {}}
+	for i, h := range hs {
+		if err := h.Register(tb, rp, cps[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hs[0].Access(Create{tb.Name, fvn}); err != nil {
+		t.Error(err)
+	}
+	for _, h := range hs {
+		if err := h.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := hs[0].Close(); err != nil {
+		t.Error(err)
+	}
+	// The first instance no longer receives the Invalidation.
+	if err := hs[1].Access(Create{tb.Name, fvn}); err != nil {
+		t.Error(err)
+	}
+	if s := cps[0].Statistics(); s.Entries != 1 {
+		t.Error(s)
+	}
+}
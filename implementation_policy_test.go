@@ -0,0 +1,104 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/mock"
+	r "github.com/heptanes/heptane/row"
+	rm "github.com/heptanes/heptane/row/mock"
+)
+
+func TestingPolicyHeptane(t *testing.T, o Options) (Heptane, *rm.Row, *cm.Cache, *[]string) {
+	reported := []string(nil)
+	o.CacheErrors = func(err CacheProviderAccessError) { reported = append(reported, err.Error()) }
+	h := NewWithOptions(o)
+	rm := &rm.Row{}
+	cm := &cm.Cache{}
+	if err := h.Register(TestingTable1(), rm, cm); err != nil {
+		t.Fatal(err)
+	}
+	return h, rm, cm, &reported
+}
+
+func TestHeptane_CachePolicy_Fallback_Retrieve(t *testing.T) {
+	h, rm, cm, reported := TestingPolicyHeptane(t, Options{CachePolicy: CacheFallback})
+	b := TestingTable1()
+	cm.Mock(c.CacheGet{Key: "table1_pk#0#s1#s2"}, errors.New("problem1"))
+	rm.Mock(r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"},
+		RetrievedValues: []r.FieldValuesByName{{"foo": "1", "bar": "2", "baz": "3"}}}, nil)
+	cm.Mock(c.CacheSet{Key: "table1_pk#0#s1#s2", Value: c.CacheValue("s3")}, errors.New("problem2"))
+	a := &Retrieve{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2"}, nil}
+	if err := h.Access(a); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1"}}` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%q", *reported); s != `["heptane.CacheGet{Key:\"table1_pk#0#s1#s2\", Value:heptane.CacheValue(nil)} Error: problem1" "heptane.CacheSet{Key:\"table1_pk#0#s1#s2\", Value:heptane.CacheValue{0x73, 0x33}} Error: problem2"]` {
+		t.Error(s)
+	}
+}
+
+func TestHeptane_CachePolicy_Fallback_Update(t *testing.T) {
+	h, rm, cm, reported := TestingPolicyHeptane(t, Options{CachePolicy: CacheFallback})
+	b := TestingTable1()
+	rm.Mock(r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}, nil)
+	cm.Mock(c.CacheSet{Key: "table1_pk#0#s1#s2", Value: c.CacheValue("s3")}, errors.New("problem1"))
+	if err := h.Access(Update{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(StaleCacheError); !ok {
+		t.Error(err)
+	} else if s := err.Error(); s != `Stale Cache for Table table1: heptane.CacheSet{Key:"table1_pk#0#s1#s2", Value:heptane.CacheValue{0x73, 0x33}} Error: problem1` {
+		t.Error(s)
+	}
+	if len(*reported) != 1 {
+		t.Error(*reported)
+	}
+}
+
+func TestHeptane_CachePolicy_Fallback_PublishError(t *testing.T) {
+	h, rm, cm, _ := TestingPolicyHeptane(t, Options{CachePolicy: CacheFallback, Bus: TestingFailingBus{}})
+	b := TestingTable1()
+	rm.Mock(r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}, nil)
+	cm.Mock(c.CacheSet{Key: "table1_pk#0#s1#s2", Value: c.CacheValue("s3")}, errors.New("problem1"))
+	err := h.Access(Create{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}})
+	if _, ok := err.(MultipleErrors); !ok {
+		t.Fatal(err)
+	}
+	if se := (StaleCacheError{}); !errors.As(err, &se) {
+		t.Error(err)
+	}
+	if pe := (BusPublishError{}); !errors.As(err, &pe) {
+		t.Error(err)
+	}
+}
+
+func TestHeptane_CachePolicy_Ignore(t *testing.T) {
+	h, rm, cm, reported := TestingPolicyHeptane(t, Options{CachePolicies: map[r.TableName]CachePolicy{"table1": CacheIgnore}})
+	b := TestingTable1()
+	rm.Mock(r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}, nil)
+	cm.Mock(c.CacheSet{Key: "table1_pk#0#s1#s2"}, errors.New("problem1"))
+	if err := h.Access(Delete{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2"}}); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprint(*reported); s != `[heptane.CacheSet{Key:"table1_pk#0#s1#s2", Value:heptane.CacheValue(nil)} Error: problem1]` {
+		t.Error(s)
+	}
+}
+
+func TestHeptane_CachePolicy_Fail(t *testing.T) {
+	h, _, cm, reported := TestingPolicyHeptane(t, Options{CachePolicy: CacheIgnore, CachePolicies: map[r.TableName]CachePolicy{"table1": CacheFail}})
+	b := TestingTable1()
+	cm.Mock(c.CacheGet{Key: "table1_pk#0#s1#s2"}, errors.New("problem1"))
+	if err := h.Access(&Retrieve{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2"}, nil}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(CacheProviderAccessError); !ok {
+		t.Error(err)
+	}
+	if *reported != nil {
+		t.Error(*reported)
+	}
+}
@@ -63,7 +63,7 @@ func TestHeptane_Retrieve_MissingPrimaryKey_RowAccessError(t *testing.T) {
 	rm.Mock(r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}, errors.New("problem1"))
 	if err := h.Access(&Retrieve{b.Name, r.FieldValuesByName{"foo": "1"}, nil}); err == nil {
 		t.Error(err)
-	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil)} Error: problem1` {
+	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false} Error: problem1` {
 		t.Error(s)
 	}
 }
@@ -133,7 +133,7 @@ func TestHeptane_Retrieve_WithPrimaryKey_RowAccessError(t *testing.T) {
 	rm.Mock(r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}, errors.New("problem1"))
 	if err := h.Access(&Retrieve{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2"}, nil}); err == nil {
 		t.Error(err)
-	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"bar":"2", "foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil)} Error: problem1` {
+	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"bar":"2", "foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false} Error: problem1` {
 		t.Error(s)
 	}
 }
@@ -193,7 +193,7 @@ func TestHeptane_Retrieve_WithCache_MissingPrimaryKey_RowAccessError(t *testing.
 	rm.Mock(r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}, errors.New("problem1"))
 	if err := h.Access(&Retrieve{b.Name, r.FieldValuesByName{"foo": "1"}, nil}); err == nil {
 		t.Error(err)
-	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil)} Error: problem1` {
+	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false} Error: problem1` {
 		t.Error(s)
 	}
 }
@@ -388,7 +388,7 @@ func TestHeptane_Retrieve_WithCache_WithPrimaryKey_CacheMiss_RowAccessError(t *t
 	a := &Retrieve{b.Name, r.FieldValuesByName{"foo": "1", "bar": "2"}, nil}
 	if err := h.Access(a); err == nil {
 		t.Error(err)
-	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"bar":"2", "foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil)} Error: problem` {
+	} else if s := err.Error(); s != `heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"bar":"2", "foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false} Error: problem` {
 		t.Error(s)
 	}
 }
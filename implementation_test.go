@@ -121,3 +121,52 @@ func TestHeptane_UnsupportedAccessTypeError(t *testing.T) {
 
 	}
 }
+
+// TestInvalidatorRow is a mock Row that records the invalidated tables.
+type TestInvalidatorRow struct {
+	rm.Row
+	Invalidated []r.TableName
+}
+
+func (p *TestInvalidatorRow) InvalidateTable(tn r.TableName) {
+	p.Invalidated = append(p.Invalidated, tn)
+}
+
+func TestHeptane_Register_InvalidateTable(t *testing.T) {
+	h := New()
+	b := TestingTable1()
+	rp1 := &TestInvalidatorRow{}
+	rp2 := &TestInvalidatorRow{}
+	if err := h.Register(b, rp1, nil); err != nil {
+		t.Error(err)
+	}
+	if err := h.Register(b, rp2, nil); err != nil {
+		t.Error(err)
+	}
+	h.Unregister(b.Name)
+	if s := fmt.Sprint(rp1.Invalidated, rp2.Invalidated); s != "[table1 table1] [table1 table1]" {
+		t.Error(s)
+	}
+}
+
+// TestVerifierRow is a mock Row whose tables never match the backend.
+type TestVerifierRow struct {
+	rm.Row
+}
+
+func (p *TestVerifierRow) VerifyTable(t r.Table) error {
+	return fmt.Errorf("Mismatch in %v", t.Name)
+}
+
+func TestHeptane_Register_VerifyTable(t *testing.T) {
+	h := New()
+	b := TestingTable1()
+	if err := h.Register(b, &TestVerifierRow{}, nil); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != "Mismatch in table1" {
+		t.Error(s)
+	}
+	if s := fmt.Sprint(h.TableNames()); s != "[]" {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,161 @@
+package heptane
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+	r "github.com/heptanes/heptane/row"
+	rm "github.com/heptanes/heptane/row/mock"
+)
+
+// TestingSlowCache is a CacheProvider answering after Latency, or after the
+// next of Latencies if there is any left.
+type TestingSlowCache struct {
+	cm.Cache
+	Latency   time.Duration
+	Latencies []time.Duration
+	m         sync.Mutex
+}
+
+func (p *TestingSlowCache) sleep() {
+	p.m.Lock()
+	d := p.Latency
+	if len(p.Latencies) > 0 {
+		d, p.Latencies = p.Latencies[0], p.Latencies[1:]
+	}
+	p.m.Unlock()
+	time.Sleep(d)
+}
+
+func (p *TestingSlowCache) Access(a c.CacheAccess) error {
+	p.sleep()
+	return p.Cache.Access(a)
+}
+
+func (p *TestingSlowCache) AccessSlice(aa []c.CacheAccess) []error {
+	p.sleep()
+	return p.Cache.AccessSlice(aa)
+}
+
+// TestingSlowRow is a RowProvider answering after Latency.
+type TestingSlowRow struct {
+	rm.Row
+	Latency time.Duration
+}
+
+func (p *TestingSlowRow) Access(a r.RowAccess) error {
+	time.Sleep(p.Latency)
+	return p.Row.Access(a)
+}
+
+func TestingTimeoutHeptane(t *testing.T, o Options, cache, row time.Duration) (Heptane, *TestingSlowRow, *TestingSlowCache) {
+	h := NewWithOptions(o)
+	rp := &TestingSlowRow{Latency: row}
+	cp := &TestingSlowCache{Latency: cache}
+	if err := h.Register(TestingTable1(), rp, cp); err != nil {
+		t.Fatal(err)
+	}
+	return h, rp, cp
+}
+
+func TestHeptane_Timeout_CacheGet(t *testing.T) {
+	o := Options{TableTimeouts: map[r.TableName]Timeouts{"table1": {Cache: time.Millisecond}}}
+	h, _, _ := TestingTimeoutHeptane(t, o, time.Second, 0)
+	err := h.Access(&Retrieve{"table1", r.FieldValuesByName{"foo": "1", "bar": "2"}, nil})
+	if e, ok := err.(CacheProviderAccessError); !ok {
+		t.Error(err)
+	} else if s := e.Err.Error(); s != `Timeout of 1ms in CacheGet of Table table1` {
+		t.Error(s)
+	}
+}
+
+func TestHeptane_Timeout_CacheIgnore(t *testing.T) {
+	o := Options{Timeouts: Timeouts{Cache: time.Millisecond}, CachePolicy: CacheIgnore}
+	h, rp, _ := TestingTimeoutHeptane(t, o, time.Second, 0)
+	b := TestingTable1()
+	rp.Mock(r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"},
+		RetrievedValues: []r.FieldValuesByName{{"foo": "1", "bar": "2", "baz": "3"}}}, nil)
+	a := &Retrieve{"table1", r.FieldValuesByName{"foo": "1", "bar": "2"}, nil}
+	if err := h.Access(a); err != nil {
+		t.Error(err)
+	} else if len(a.RetrievedValues) != 1 {
+		t.Error(a.RetrievedValues)
+	}
+}
+
+func TestHeptane_Timeout_Timed(t *testing.T) {
+	o := Options{Timeouts: Timeouts{Row: time.Hour}}
+	h, _, _ := TestingTimeoutHeptane(t, o, 0, time.Second)
+	a := Timed{Access: Delete{"table1", r.FieldValuesByName{"foo": "1", "bar": "2"}}, Timeouts: &Timeouts{Row: time.Millisecond}}
+	err := h.Access(a)
+	if e, ok := err.(RowProviderAccessError); !ok {
+		t.Error(err)
+	} else if e, ok := e.Err.(TimeoutError); !ok {
+		t.Error(e)
+	} else if e.Phase != RowDeletePhase || e.Timeout != time.Millisecond {
+		t.Error(e)
+	}
+	if e := (TimeoutError{}); !errors.As(MultipleErrors{[]error{errors.New("problem"), err}}, &e) {
+		t.Error(err)
+	}
+}
+
+func TestHeptane_Timeout_Deadline(t *testing.T) {
+	h, _, _ := TestingTimeoutHeptane(t, Options{}, 0, 0)
+	a := &Timed{Access: Create{"table1", r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}, Deadline: time.Now()}
+	err := h.Access(a)
+	if e, ok := err.(RowProviderAccessError); !ok {
+		t.Error(err)
+	} else if s := e.Err.Error(); s != `Timeout of 0s in RowCreate of Table table1` {
+		t.Error(s)
+	}
+}
+
+func TestHeptane_Timeout_RowWrite(t *testing.T) {
+	o := Options{Timeouts: Timeouts{Row: time.Millisecond}}
+	h, _, cp := TestingTimeoutHeptane(t, o, 0, time.Second)
+	cp.Cache.Access(c.CacheSet{Key: "table1_pk#0#s1#s2", Value: c.CacheValue("s3")})
+	err := h.Access(Update{"table1", r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "4"}})
+	if e, ok := err.(RowProviderAccessError); !ok {
+		t.Error(err)
+	} else if _, ok := e.Err.(TimeoutError); !ok {
+		t.Error(e)
+	}
+	// The RowUpdate may still be performed, so the cache entry is deleted.
+	cg := &c.CacheGet{Key: "table1_pk#0#s1#s2"}
+	if err := cp.Cache.Access(cg); err != nil {
+		t.Error(err)
+	} else if cg.Value != nil {
+		t.Error(cg.Value)
+	}
+}
+
+func TestHeptane_Timeout_CacheSetOrder(t *testing.T) {
+	o := Options{Timeouts: Timeouts{Cache: time.Millisecond}, CachePolicy: CacheIgnore}
+	h, rp, cp := TestingTimeoutHeptane(t, o, 0, 0)
+	cp.Latencies = []time.Duration{50 * time.Millisecond}
+	b := TestingTable1()
+	for _, v := range []string{"3", "4"} {
+		rp.Mock(r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": v}}, nil)
+	}
+	// The first CacheSet is abandoned, the second one waits for it.
+	if err := h.Access(Update{"table1", r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}); err != nil {
+		t.Error(err)
+	}
+	a := Timed{Access: Update{"table1", r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "4"}}, Timeouts: &Timeouts{Cache: time.Second}}
+	if err := h.Access(a); err != nil {
+		t.Error(err)
+	}
+	// Even after the abandoned CacheSet completes.
+	time.Sleep(100 * time.Millisecond)
+	cg := &c.CacheGet{Key: "table1_pk#0#s1#s2"}
+	if err := cp.Cache.Access(cg); err != nil {
+		t.Error(err)
+	} else if string(cg.Value) != "s4" {
+		t.Error(string(cg.Value))
+	}
+}
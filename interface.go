@@ -1,6 +1,9 @@
 package heptane
 
 import (
+	"time"
+
+	b "github.com/heptanes/heptane/bus"
 	c "github.com/heptanes/heptane/cache"
 	r "github.com/heptanes/heptane/row"
 )
@@ -45,12 +48,89 @@ type Delete struct {
 	FieldValues r.FieldValuesByName
 }
 
+// Timed specifies an Access bounded in time, overriding the Timeouts of its
+// table.
+type Timed struct {
+	// Access is the bounded Access.
+	Access Access
+	// Timeouts, if not nil, replaces the Timeouts of the table.
+	Timeouts *Timeouts
+	// Deadline, if not zero, is the time when every phase of the Access
+	// expires.
+	Deadline time.Time
+}
+
+// Timeouts bounds the time of each phase of an Access. Zero means no bound.
+type Timeouts struct {
+	// Cache bounds each access to the CacheProvider.
+	Cache time.Duration
+	// Row bounds each access to the RowProvider.
+	Row time.Duration
+}
+
+// Phase is a step of an Access that may time out.
+type Phase string
+
+const (
+	// CacheGetPhase is the CacheGet of a Retrieve.
+	CacheGetPhase Phase = "CacheGet"
+	// CacheSetPhase is the CacheSet of a written or retrieved row.
+	CacheSetPhase Phase = "CacheSet"
+	// RowCreatePhase is the RowCreate of a Create.
+	RowCreatePhase Phase = "RowCreate"
+	// RowRetrievePhase is the RowRetrieve of a Retrieve, or of an Update
+	// missing some values.
+	RowRetrievePhase Phase = "RowRetrieve"
+	// RowUpdatePhase is the RowUpdate of an Update.
+	RowUpdatePhase Phase = "RowUpdate"
+	// RowDeletePhase is the RowDelete of a Delete.
+	RowDeletePhase Phase = "RowDelete"
+)
+
+// CachePolicy specifies how the errors of the CacheProvider of a table are
+// handled.
+type CachePolicy int
+
+const (
+	// CacheFail returns the errors of the CacheProvider as
+	// CacheProviderAccessErrors.
+	CacheFail CachePolicy = iota
+	// CacheFallback handles a failed CacheGet as a miss, so the row is
+	// retrieved from the RowProvider, and returns the error of the CacheSet
+	// after a Create, Update or Delete as a StaleCacheError.
+	CacheFallback
+	// CacheIgnore handles a failed CacheGet as a miss and ignores the
+	// errors of the CacheSets.
+	CacheIgnore
+)
+
+// Options contains the optional parameters of a Heptane.
+type Options struct {
+	// CachePolicy is the CachePolicy of the tables not in CachePolicies.
+	CachePolicy CachePolicy
+	// CachePolicies contains the CachePolicy of each table.
+	CachePolicies map[r.TableName]CachePolicy
+	// CacheErrors, if not nil, is called with each error of a CacheProvider
+	// handled by a CachePolicy other than CacheFail, so they are not lost.
+	CacheErrors func(CacheProviderAccessError)
+	// Timeouts are the Timeouts of the tables not in TableTimeouts.
+	Timeouts Timeouts
+	// TableTimeouts contains the Timeouts of each table.
+	TableTimeouts map[r.TableName]Timeouts
+	// Bus, if not nil, is where an Invalidation is published after each
+	// Create, Update and Delete of a table with a CacheProvider, and where
+	// the Invalidations of the other instances are received to invalidate
+	// the CacheProviders implementing CacheInvalidator.
+	Bus b.Bus
+}
+
 // Heptane is the main interface, it provides a uniform access to tables
 // supported by different RowProviders and CacheProviders. Safe to be used from
 // different goroutines.
 type Heptane interface {
 	// Register creates and updates a mapping between a TableName and its
-	// specification: the Table, RowProvider and CacheProvider.
+	// specification: the Table, RowProvider and CacheProvider. The Table is
+	// verified if the RowProvider implements TableVerifier.
 	Register(r.Table, r.RowProvider, c.CacheProvider) error
 	// Unregister deletes the mapping between the given Tablename and its
 	// specification.
@@ -70,4 +150,7 @@ type Heptane interface {
 	Access(Access) error
 	// AccessSlice performs several acccesses to the table using the cache.
 	AccessSlice([]Access) []error
+	// Close unsubscribes the instance from the Bus, if any, so it no
+	// longer receives the Invalidations of the other instances.
+	Close() error
 }
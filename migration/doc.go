@@ -0,0 +1,23 @@
+/*
+Migration of the rows of a table between RowProviders.
+
+A Migration copies every partition of a Table from a Source RowProvider to a
+Destination RowProvider. The partitions are either given explicitly or
+enumerated by the Source, which must implement PartitionScanner in that case.
+
+Several partitions are migrated concurrently according to the Parallelism, and
+the rows of each partition are written to the Destination with AccessSlice in
+groups of BatchSize. A RowCreate that fails in the Destination with an error
+matching ErrDuplicateKey of row is retried as a RowUpdate, so rows already
+present in the Destination are overwritten. Any other error fails the
+partition.
+
+After each partition is migrated, its rows may be verified: the number of rows
+and a checksum of their contents are compared between the Source and the
+Destination. The PrimaryKey cache of the Destination may be warmed with the
+migrated rows or invalidated.
+
+The migrated partitions are recorded in a Checkpoint that can be stored and
+given to a later Run to resume an interrupted migration.
+*/
+package heptane
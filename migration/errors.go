@@ -0,0 +1,45 @@
+package heptane
+
+import (
+	"fmt"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// MissingPartitionScannerError is produced when a Migration without explicit
+// Partitions has a Source that does not implement PartitionScanner.
+type MissingPartitionScannerError struct {
+	TableName r.TableName
+}
+
+func (e MissingPartitionScannerError) Error() string {
+	return fmt.Sprintf("Missing PartitionScanner in Source of Table %v", e.TableName)
+}
+
+// PartitionError is produced when the migration of a partition fails.
+type PartitionError struct {
+	Partition r.FieldValuesByName
+	Err       error
+}
+
+func (e PartitionError) Error() string {
+	return fmt.Sprintf("Partition %v Error: %v", e.Partition, e.Err)
+}
+
+func (e PartitionError) Unwrap() error {
+	return e.Err
+}
+
+// VerificationError is produced when the rows of a partition in the Source
+// and in the Destination differ after the migration.
+type VerificationError struct {
+	SourceRows          int
+	DestinationRows     int
+	SourceChecksum      string
+	DestinationChecksum string
+}
+
+func (e VerificationError) Error() string {
+	return fmt.Sprintf("Verification failed: %v rows with checksum %v in Source, %v rows with checksum %v in Destination",
+		e.SourceRows, e.SourceChecksum, e.DestinationRows, e.DestinationChecksum)
+}
@@ -0,0 +1,288 @@
+package heptane
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+
+	h "github.com/heptanes/heptane"
+	c "github.com/heptanes/heptane/cache"
+	r "github.com/heptanes/heptane/row"
+)
+
+// CacheMode specifies what a Migration does with the PrimaryKey cache of the
+// Destination.
+type CacheMode int
+
+const (
+	// CacheNone leaves the cache untouched.
+	CacheNone CacheMode = iota
+	// CacheWarm stores every migrated row in the cache.
+	CacheWarm
+	// CacheInvalidate stores in the cache that every migrated row is
+	// unknown, so the next Retrieve reads it from the Destination.
+	CacheInvalidate
+)
+
+// Checkpoint records the partitions already migrated. It may be serialized as
+// JSON.
+type Checkpoint struct {
+	// Done contains the encoded PartitionKey of every migrated partition.
+	Done map[string]bool `json:"done"`
+}
+
+// Migration copies all the rows of a Table from a Source RowProvider to a
+// Destination RowProvider.
+type Migration struct {
+	// Table is the specification of the table in both RowProviders.
+	Table r.Table
+	// Source is the RowProvider the rows are read from.
+	Source r.RowProvider
+	// Destination is the RowProvider the rows are written to.
+	Destination r.RowProvider
+	// Partitions contains the PartitionKeys of the partitions to migrate.
+	// When nil, the Source must implement PartitionScanner and all its
+	// partitions are migrated.
+	Partitions []r.FieldValuesByName
+	// Parallelism is the number of partitions migrated concurrently. Values
+	// lower than 1 mean 1.
+	Parallelism int
+	// BatchSize is the maximum number of RowAccesses sent to the Destination
+	// in a single AccessSlice. Values lower than 1 mean 1.
+	BatchSize int
+	// Verify enables the comparison of the number of rows and the checksum
+	// of each partition in the Source and in the Destination.
+	Verify bool
+	// Cache is the CacheProvider of the Destination.
+	Cache c.CacheProvider
+	// CacheMode specifies how the Cache is updated. It is ignored when
+	// Cache is nil or the Table has no PrimaryKeyCachePrefix.
+	CacheMode CacheMode
+	// OnCheckpoint, if not nil, is called with the updated Checkpoint after
+	// each migrated partition. Calls are never concurrent.
+	OnCheckpoint func(Checkpoint)
+}
+
+// PartitionID returns the encoded PartitionKey of a partition, as stored in a
+// Checkpoint.
+func PartitionID(t r.Table, partition r.FieldValuesByName) string {
+	return hex.EncodeToString(t.Encode(t.PartitionKey, partition))
+}
+
+// Run migrates all the partitions not contained in the given Checkpoint and
+// returns the updated Checkpoint. An error in a partition does not stop the
+// migration of the other partitions, but the partition is not added to the
+// Checkpoint so a later Run retries it.
+func (m *Migration) Run(cp Checkpoint) (Checkpoint, error) {
+	if err := m.Table.Validate(); err != nil {
+		return cp, err
+	}
+	partitions := m.Partitions
+	if partitions == nil {
+		ps, ok := m.Source.(r.PartitionScanner)
+		if !ok {
+			return cp, MissingPartitionScannerError{m.Table.Name}
+		}
+		var err error
+		if partitions, err = ps.ScanPartitions(m.Table); err != nil {
+			return cp, err
+		}
+	}
+	done := make(map[string]bool, len(cp.Done)+len(partitions))
+	for id := range cp.Done {
+		done[id] = true
+	}
+	parallelism := m.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	mu := sync.Mutex{}
+	errs := []error(nil)
+	ch := make(chan r.FieldValuesByName)
+	wg := sync.WaitGroup{}
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partition := range ch {
+				err := m.migrate(partition)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, PartitionError{partition, err})
+				} else {
+					done[PartitionID(m.Table, partition)] = true
+					if m.OnCheckpoint != nil {
+						m.OnCheckpoint(copyCheckpoint(done))
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, partition := range partitions {
+		mu.Lock()
+		skip := done[PartitionID(m.Table, partition)]
+		mu.Unlock()
+		if !skip {
+			ch <- partition
+		}
+	}
+	close(ch)
+	wg.Wait()
+	return copyCheckpoint(done), collapse(errs)
+}
+
+// collapse returns nil, the only error or MultipleErrors.
+func collapse(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return h.MultipleErrors{Errors: errs}
+}
+
+func copyCheckpoint(done map[string]bool) Checkpoint {
+	cp := Checkpoint{Done: make(map[string]bool, len(done))}
+	for id := range done {
+		cp.Done[id] = true
+	}
+	return cp
+}
+
+func (m *Migration) migrate(partition r.FieldValuesByName) error {
+	rr := r.RowRetrieve{Table: m.Table, FieldValues: partition, Consistent: true}
+	if err := m.Source.Access(&rr); err != nil {
+		return h.RowProviderAccessError{Access: rr, Err: err}
+	}
+	if err := m.write(rr.RetrievedValues); err != nil {
+		return err
+	}
+	if m.Verify {
+		if err := m.verify(partition, rr.RetrievedValues); err != nil {
+			return err
+		}
+	}
+	return m.cache(rr.RetrievedValues)
+}
+
+func (m *Migration) batchSize() int {
+	if m.BatchSize < 1 {
+		return 1
+	}
+	return m.BatchSize
+}
+
+func (m *Migration) write(rows []r.FieldValuesByName) error {
+	n := m.batchSize()
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > n {
+			batch = batch[:n]
+		}
+		rows = rows[len(batch):]
+		rcs := make([]r.RowAccess, len(batch))
+		for i, fvn := range batch {
+			rcs[i] = r.RowCreate{Table: m.Table, FieldValues: fvn}
+		}
+		rus := []r.RowAccess(nil)
+		errs := []error(nil)
+		for i, err := range m.Destination.AccessSlice(rcs) {
+			if errors.Is(err, r.ErrDuplicateKey) {
+				rus = append(rus, r.RowUpdate{Table: m.Table, FieldValues: batch[i]})
+			} else if err != nil {
+				errs = append(errs, h.RowProviderAccessError{Access: rcs[i], Err: err})
+			}
+		}
+		if len(rus) == 0 {
+			if err := collapse(errs); err != nil {
+				return err
+			}
+			continue
+		}
+		for i, err := range m.Destination.AccessSlice(rus) {
+			if err != nil {
+				errs = append(errs, h.RowProviderAccessError{Access: rus[i], Err: err})
+			}
+		}
+		if err := collapse(errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migration) verify(partition r.FieldValuesByName, rows []r.FieldValuesByName) error {
+	rr := r.RowRetrieve{Table: m.Table, FieldValues: partition, Consistent: true}
+	if err := m.Destination.Access(&rr); err != nil {
+		return h.RowProviderAccessError{Access: rr, Err: err}
+	}
+	sc := checksum(m.Table, rows)
+	dc := checksum(m.Table, rr.RetrievedValues)
+	if len(rows) != len(rr.RetrievedValues) || sc != dc {
+		return VerificationError{len(rows), len(rr.RetrievedValues), sc, dc}
+	}
+	return nil
+}
+
+// checksum returns a digest of the contents of the given rows that does not
+// depend on their order.
+func checksum(t r.Table, rows []r.FieldValuesByName) string {
+	fns := make([]r.FieldName, 0, len(t.PrimaryKey)+len(t.Values))
+	fns = append(fns, t.PrimaryKey...)
+	fns = append(fns, t.Values...)
+	encoded := make([][]byte, len(rows))
+	for i, fvn := range rows {
+		encoded[i] = t.Encode(fns, fvn)
+	}
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	d := sha256.New()
+	for _, q := range encoded {
+		d.Write(q)
+	}
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+func (m *Migration) cache(rows []r.FieldValuesByName) error {
+	if m.Cache == nil || m.Table.PrimaryKeyCachePrefix == nil || m.CacheMode == CacheNone {
+		return nil
+	}
+	n := m.batchSize()
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > n {
+			batch = batch[:n]
+		}
+		rows = rows[len(batch):]
+		css := make([]c.CacheAccess, len(batch))
+		for i, fvn := range batch {
+			key, err := h.CacheKey(m.Table, fvn)
+			if err != nil {
+				return err
+			}
+			value := c.CacheValue(nil)
+			if m.CacheMode == CacheWarm {
+				if value, err = h.CacheValue(m.Table, fvn); err != nil {
+					return err
+				}
+			}
+			css[i] = c.CacheSet{Key: key, Value: value}
+		}
+		errs := []error(nil)
+		for i, err := range m.Cache.AccessSlice(css) {
+			if err != nil {
+				errs = append(errs, h.CacheProviderAccessError{Access: css[i], Err: err})
+			}
+		}
+		if err := collapse(errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
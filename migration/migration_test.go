@@ -0,0 +1,295 @@
+package heptane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	h "github.com/heptanes/heptane"
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/mock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestingTable1() r.Table {
+	return r.Table{
+		Name:                  "table1",
+		PartitionKey:          []r.FieldName{"foo"},
+		PrimaryKey:            []r.FieldName{"foo", "bar"},
+		Values:                []r.FieldName{"baz"},
+		Types:                 r.FieldTypesByName{"foo": "string", "bar": "string", "baz": "string"},
+		PrimaryKeyCachePrefix: []string{"table1_pk", "0"},
+	}
+}
+
+// TestRow is a RowProvider that keeps the rows of a single table in memory.
+type TestRow struct {
+	m         sync.Mutex
+	rows      map[string]r.FieldValuesByName
+	CreateErr error
+	UpdateErr error
+}
+
+func NewTestRow(rows ...r.FieldValuesByName) *TestRow {
+	p := &TestRow{rows: map[string]r.FieldValuesByName{}}
+	for _, fvn := range rows {
+		p.rows[fmt.Sprint(fvn["foo"], "#", fvn["bar"])] = fvn
+	}
+	return p
+}
+
+func (p *TestRow) Access(a r.RowAccess) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	switch a := a.(type) {
+	case r.RowCreate:
+		if p.CreateErr != nil {
+			return p.CreateErr
+		}
+		p.rows[fmt.Sprint(a.FieldValues["foo"], "#", a.FieldValues["bar"])] = a.FieldValues
+		return nil
+	case r.RowUpdate:
+		if p.UpdateErr != nil {
+			return p.UpdateErr
+		}
+		p.rows[fmt.Sprint(a.FieldValues["foo"], "#", a.FieldValues["bar"])] = a.FieldValues
+		return nil
+	case *r.RowRetrieve:
+		a.RetrievedValues = nil
+		for _, fvn := range p.rows {
+			if fvn["foo"] == a.FieldValues["foo"] {
+				a.RetrievedValues = append(a.RetrievedValues, fvn)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported: %#v", a)
+}
+
+func (p *TestRow) AccessSlice(aa []r.RowAccess) (errs []error) {
+	for _, a := range aa {
+		errs = append(errs, p.Access(a))
+	}
+	return
+}
+
+func (p *TestRow) keys() []string {
+	p.m.Lock()
+	defer p.m.Unlock()
+	ks := []string{}
+	for k := range p.rows {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// TestScanRow is a TestRow that implements PartitionScanner.
+type TestScanRow struct {
+	*TestRow
+}
+
+func (p TestScanRow) ScanPartitions(t r.Table) ([]r.FieldValuesByName, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	seen := map[interface{}]bool{}
+	fvns := []r.FieldValuesByName{}
+	for _, fvn := range p.rows {
+		if !seen[fvn["foo"]] {
+			seen[fvn["foo"]] = true
+			fvns = append(fvns, r.FieldValuesByName{"foo": fvn["foo"]})
+		}
+	}
+	sort.Slice(fvns, func(i, j int) bool {
+		return fmt.Sprint(fvns[i]["foo"]) < fmt.Sprint(fvns[j]["foo"])
+	})
+	return fvns, nil
+}
+
+func TestingRows() []r.FieldValuesByName {
+	return []r.FieldValuesByName{
+		{"foo": "1", "bar": "2", "baz": "3"},
+		{"foo": "1", "bar": "4", "baz": "5"},
+		{"foo": "6", "bar": "7", "baz": "8"},
+	}
+}
+
+func TestMigration_Run_ValidationError(t *testing.T) {
+	b := TestingTable1()
+	b.Name = ""
+	m := Migration{Table: b}
+	if _, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Empty TableName in Table` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_MissingPartitionScanner(t *testing.T) {
+	m := Migration{Table: TestingTable1(), Source: NewTestRow(), Destination: NewTestRow()}
+	if _, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Missing PartitionScanner in Source of Table table1` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_Partitions(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow()
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "1"}}, Verify: true}
+	if cp, err := m.Run(Checkpoint{}); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprint(cp.Done); s != `map[730131:true]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprint(dst.keys()); s != `[1#2 1#4]` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_ScanPartitions(t *testing.T) {
+	src := TestScanRow{NewTestRow(TestingRows()...)}
+	dst := NewTestRow()
+	checkpoints := 0
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Parallelism: 2, BatchSize: 2, Verify: true,
+		OnCheckpoint: func(Checkpoint) { checkpoints++ }}
+	if cp, err := m.Run(Checkpoint{}); err != nil {
+		t.Error(err)
+	} else if l := len(cp.Done); l != 2 {
+		t.Error(l)
+	}
+	if checkpoints != 2 {
+		t.Error(checkpoints)
+	}
+	if s := fmt.Sprint(dst.keys()); s != `[1#2 1#4 6#7]` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_Resume(t *testing.T) {
+	src := TestScanRow{NewTestRow(TestingRows()...)}
+	dst := NewTestRow()
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst}
+	cp := Checkpoint{Done: map[string]bool{PartitionID(m.Table, r.FieldValuesByName{"foo": "1"}): true}}
+	q, err := json.Marshal(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp = Checkpoint{}
+	if err := json.Unmarshal(q, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err := m.Run(cp); err != nil {
+		t.Error(err)
+	} else if l := len(cp.Done); l != 2 {
+		t.Error(l)
+	}
+	if s := fmt.Sprint(dst.keys()); s != `[6#7]` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_Overwrite(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow(r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "old"})
+	dst.CreateErr = fmt.Errorf("duplicate: %w", r.ErrDuplicateKey)
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "1"}}, Verify: true}
+	if _, err := m.Run(Checkpoint{}); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprint(dst.rows["1#2"]); s != `map[bar:2 baz:3 foo:1]` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_WriteError(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow()
+	dst.CreateErr = fmt.Errorf("duplicate: %w", r.ErrDuplicateKey)
+	dst.UpdateErr = errors.New("problem2")
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "6"}}}
+	if cp, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Partition map[foo:6] Error: heptane.RowUpdate{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"bar":"7", "baz":"8", "foo":"6"}} Error: problem2` {
+		t.Error(s)
+	} else if l := len(cp.Done); l != 0 {
+		t.Error(l)
+	}
+}
+
+func TestMigration_Run_CreateError(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow()
+	dst.CreateErr = errors.New("problem1")
+	dst.UpdateErr = errors.New("problem2")
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "6"}}}
+	if _, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if rpae := (h.RowProviderAccessError{}); !errors.As(err, &rpae) {
+		t.Error(err)
+	} else if _, ok := rpae.Access.(r.RowCreate); !ok || rpae.Err.Error() != "problem1" {
+		t.Error(err)
+	}
+}
+
+// TestLossyRow is a TestRow that forgets the Values of the created rows.
+type TestLossyRow struct {
+	*TestRow
+}
+
+func (p TestLossyRow) AccessSlice(aa []r.RowAccess) (errs []error) {
+	for _, a := range aa {
+		if rc, ok := a.(r.RowCreate); ok {
+			a = r.RowCreate{Table: rc.Table, FieldValues: r.FieldValuesByName{"foo": rc.FieldValues["foo"], "bar": rc.FieldValues["bar"]}}
+		}
+		errs = append(errs, p.Access(a))
+	}
+	return
+}
+
+func TestMigration_Run_VerificationError(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := TestLossyRow{NewTestRow()}
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "6"}}, Verify: true}
+	if _, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Partition map[foo:6] Error: Verification failed: 1 rows with checksum bfd07b10160beade063a2ee89507796711bcb557312748ae93de60be16d432f4 in Source, 1 rows with checksum ed1e5e3a018fda229417148efd6db7e95325c5b592d3ce5fe96ae42e4e7c3f3b in Destination` {
+		t.Error(s)
+	}
+}
+
+func TestMigration_Run_CacheWarm(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow()
+	cache := &cm.Cache{}
+	cache.Mock(c.CacheSet{Key: "table1_pk#0#s6#s7", Value: c.CacheValue("s8")}, nil)
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "6"}}, Cache: cache, CacheMode: CacheWarm}
+	if _, err := m.Run(Checkpoint{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigration_Run_CacheInvalidate(t *testing.T) {
+	src := NewTestRow(TestingRows()...)
+	dst := NewTestRow()
+	cache := &cm.Cache{}
+	cache.Mock(c.CacheSet{Key: "table1_pk#0#s6#s7", Value: nil}, errors.New("problem"))
+	m := Migration{Table: TestingTable1(), Source: src, Destination: dst,
+		Partitions: []r.FieldValuesByName{{"foo": "6"}}, Cache: cache, CacheMode: CacheInvalidate}
+	if _, err := m.Run(Checkpoint{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Partition map[foo:6] Error: heptane.CacheSet{Key:"table1_pk#0#s6#s7", Value:heptane.CacheValue(nil)} Error: problem` {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,17 @@
+/*
+Retries with exponential backoff for CacheProviders and RowProviders.
+
+Cache and Row wrap any CacheProvider and RowProvider, performing again the
+accesses that fail with a retryable error according to their Policy. The
+waits between attempts grow exponentially with jitter. By default an error is
+retryable if it has a method Retryable returning true, like the transient
+errors of the RowProvider in row/sql, or if it is a timeout.
+
+RowCreates are not idempotent, because a RowCreate whose response was lost may
+have created the row, so they are retried only if RetryCreates is true. For
+the same reason a RowUpdate or RowDelete that fails with ErrNotFound of row
+when retried is successful. The
+errors of the accesses performed more than once are AttemptsErrors, with the
+number of attempts.
+*/
+package heptane
@@ -0,0 +1,20 @@
+package heptane
+
+import (
+	"fmt"
+)
+
+// AttemptsError is produced when an access still fails after being performed
+// several times. Err is the error of the last attempt.
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+func (e AttemptsError) Error() string {
+	return fmt.Sprintf("Failed after %v Attempts: %v", e.Attempts, e.Err)
+}
+
+func (e AttemptsError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,222 @@
+package heptane
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	r "github.com/heptanes/heptane/row"
+)
+
+// Default values of the fields of Policy.
+const (
+	DefaultAttempts   = 3
+	DefaultBackoff    = 10 * time.Millisecond
+	DefaultMaxBackoff = time.Second
+)
+
+// Retryable is the default classifier of Policy. It reports whether the error
+// has a method Retryable returning true or is a timeout.
+func Retryable(err error) bool {
+	if re := interface{ Retryable() bool }(nil); errors.As(err, &re) {
+		return re.Retryable()
+	}
+	if ne := net.Error(nil); errors.As(err, &ne) {
+		return ne.Timeout()
+	}
+	return false
+}
+
+// Policy decides which accesses are performed again and when. The zero value
+// is a Policy with the default values.
+type Policy struct {
+	// Attempts is the maximum number of times an access is performed.
+	// Zero means DefaultAttempts.
+	Attempts int
+	// Backoff is the wait before the second attempt, doubled before each
+	// following attempt. Each wait is a random duration between the half
+	// and the whole of its value. Zero means DefaultBackoff.
+	Backoff time.Duration
+	// MaxBackoff is the maximum wait between two attempts. Zero means
+	// DefaultMaxBackoff.
+	MaxBackoff time.Duration
+	// Retryable decides whether an error is retryable. Nil means the
+	// function Retryable.
+	Retryable func(error) bool
+	// RetryCreates makes the RowCreates retryable, which must be set only if
+	// performing them twice is harmless.
+	RetryCreates bool
+	// Retries, if not nil, is called with the error of each attempt that is
+	// going to be retried.
+	Retries func(error)
+
+	sleep func(time.Duration)
+}
+
+// wait sleeps before the given attempt, starting from 1 for the second one.
+func (p Policy) wait(attempt int) {
+	d := p.Backoff
+	if d <= 0 {
+		d = DefaultBackoff
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if p.sleep != nil {
+		p.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// run performs the accesses with the given function, and then again the
+// failed ones while all of them are retryable. The errors of the accesses
+// performed more than once are AttemptsErrors.
+func (p Policy) run(n int, idempotent func(int) bool, access func([]int) []error) []error {
+	attempts := p.Attempts
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	errs := make([]error, n)
+	pending := make([]int, n)
+	for i := range pending {
+		pending[i] = i
+	}
+	for attempt := 1; ; attempt++ {
+		failed := []int(nil)
+		for j, err := range access(pending) {
+			if errs[pending[j]] = err; err != nil {
+				failed = append(failed, pending[j])
+			}
+		}
+		// A failed access is retried only if all of them are, so the
+		// accesses of a transaction are retried together.
+		retry := failed != nil && attempt < attempts
+		for _, i := range failed {
+			if !retry {
+				break
+			}
+			retry = retryable(errs[i]) && idempotent(i)
+		}
+		if !retry {
+			if attempt > 1 {
+				for _, i := range failed {
+					errs[i] = AttemptsError{attempt, errs[i]}
+				}
+			}
+			return errs
+		}
+		if p.Retries != nil {
+			for _, i := range failed {
+				p.Retries(errs[i])
+			}
+		}
+		p.wait(attempt)
+		pending = failed
+	}
+}
+
+// Cache implements CacheProvider, performing the CacheAccesses in
+// CacheProvider according to Policy. Every CacheAccess is idempotent.
+type Cache struct {
+	c.CacheProvider
+	Policy
+}
+
+// Access implements CacheProvider.
+func (p Cache) Access(a c.CacheAccess) error {
+	return p.AccessSlice([]c.CacheAccess{a})[0]
+}
+
+// AccessSlice implements CacheProvider. The failed CacheAccesses are
+// performed again in a single AccessSlice if all of them are retryable.
+func (p Cache) AccessSlice(aa []c.CacheAccess) []error {
+	return p.Policy.run(len(aa), func(int) bool { return true }, func(is []int) []error {
+		bb := make([]c.CacheAccess, len(is))
+		for j, i := range is {
+			bb[j] = aa[i]
+		}
+		return p.CacheProvider.AccessSlice(bb)
+	})
+}
+
+// Invalidate implements CacheInvalidator if CacheProvider does.
+func (p Cache) Invalidate(keys ...c.CacheKey) {
+	if ci, ok := p.CacheProvider.(c.CacheInvalidator); ok {
+		ci.Invalidate(keys...)
+	}
+}
+
+// Row implements RowProvider, performing the RowAccesses in RowProvider
+// according to Policy.
+type Row struct {
+	r.RowProvider
+	Policy
+}
+
+// Access implements RowProvider.
+func (p Row) Access(a r.RowAccess) error {
+	return p.AccessSlice([]r.RowAccess{a})[0]
+}
+
+// AccessSlice implements RowProvider. The failed RowAccesses are performed
+// again in a single AccessSlice if all of them are retryable. A RowUpdate or
+// RowDelete failing with ErrNotFound after the first attempt succeeds, since
+// the previous attempt may have been performed without its response.
+func (p Row) AccessSlice(aa []r.RowAccess) []error {
+	retried := false
+	return p.Policy.run(len(aa), func(i int) bool {
+		switch aa[i].(type) {
+		case r.RowCreate, *r.RowCreate:
+			return p.RetryCreates
+		}
+		return true
+	}, func(is []int) []error {
+		bb := make([]r.RowAccess, len(is))
+		for j, i := range is {
+			bb[j] = aa[i]
+		}
+		errs := p.RowProvider.AccessSlice(bb)
+		if retried {
+			for j, err := range errs {
+				switch bb[j].(type) {
+				case r.RowUpdate, *r.RowUpdate, r.RowDelete, *r.RowDelete:
+					if errors.Is(err, r.ErrNotFound) {
+						errs[j] = nil
+					}
+				}
+			}
+		}
+		retried = true
+		return errs
+	})
+}
+
+// VerifyTable implements TableVerifier if RowProvider does.
+func (p Row) VerifyTable(t r.Table) error {
+	if tv, ok := p.RowProvider.(r.TableVerifier); ok {
+		return tv.VerifyTable(t)
+	}
+	return nil
+}
+
+// InvalidateTable implements TableInvalidator if RowProvider does.
+func (p Row) InvalidateTable(tn r.TableName) {
+	if ti, ok := p.RowProvider.(r.TableInvalidator); ok {
+		ti.InvalidateTable(tn)
+	}
+}
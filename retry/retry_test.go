@@ -0,0 +1,181 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	c "github.com/heptanes/heptane/cache"
+	cm "github.com/heptanes/heptane/cache/memory"
+	r "github.com/heptanes/heptane/row"
+)
+
+type TestingRetryableError struct{}
+
+func (e TestingRetryableError) Error() string   { return "transient" }
+func (e TestingRetryableError) Retryable() bool { return true }
+
+// TestingRow is a RowProvider failing the first Failures calls of each
+// RowAccess with Err, and the following ones with Then.
+type TestingRow struct {
+	Failures int
+	Err      error
+	Then     error
+	Calls    []int
+}
+
+func (p *TestingRow) Access(a r.RowAccess) error {
+	return p.AccessSlice([]r.RowAccess{a})[0]
+}
+
+func (p *TestingRow) AccessSlice(aa []r.RowAccess) []error {
+	p.Calls = append(p.Calls, len(aa))
+	errs := make([]error, len(aa))
+	for i := range errs {
+		if len(p.Calls) <= p.Failures {
+			errs[i] = p.Err
+		} else {
+			errs[i] = p.Then
+		}
+	}
+	return errs
+}
+
+func TestingPolicy(waits *[]time.Duration) Policy {
+	return Policy{sleep: func(d time.Duration) { *waits = append(*waits, d) }}
+}
+
+func TestRow_Retried(t *testing.T) {
+	waits := []time.Duration(nil)
+	tr := &TestingRow{Failures: 2, Err: TestingRetryableError{}}
+	p := Row{tr, TestingPolicy(&waits)}
+	if err := p.Access(r.RowDelete{}); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprint(tr.Calls); s != `[1 1 1]` {
+		t.Error(s)
+	}
+	if len(waits) != 2 || waits[0] < DefaultBackoff/2 || waits[0] > DefaultBackoff || waits[1] < DefaultBackoff || waits[1] > 2*DefaultBackoff {
+		t.Error(waits)
+	}
+}
+
+func TestRow_Exhausted(t *testing.T) {
+	waits := []time.Duration(nil)
+	tr := &TestingRow{Failures: 5, Err: TestingRetryableError{}}
+	p := Row{tr, TestingPolicy(&waits)}
+	retries := 0
+	p.Retries = func(error) { retries++ }
+	if err := p.Access(r.RowUpdate{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Failed after 3 Attempts: transient` {
+		t.Error(s)
+	}
+	if retries != 2 {
+		t.Error(retries)
+	}
+}
+
+func TestRow_NotRetryable(t *testing.T) {
+	waits := []time.Duration(nil)
+	tr := &TestingRow{Failures: 1, Err: errors.New("problem")}
+	p := Row{tr, TestingPolicy(&waits)}
+	if err := p.Access(r.RowUpdate{}); err == nil || err.Error() != "problem" {
+		t.Error(err)
+	}
+	if s := fmt.Sprint(tr.Calls); s != `[1]` {
+		t.Error(s)
+	}
+}
+
+func TestRow_Create(t *testing.T) {
+	waits := []time.Duration(nil)
+	tr := &TestingRow{Failures: 1, Err: TestingRetryableError{}}
+	p := Row{tr, TestingPolicy(&waits)}
+	if err := p.Access(r.RowCreate{}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(TestingRetryableError); !ok {
+		t.Error(err)
+	}
+	p.RetryCreates = true
+	tr.Calls = nil
+	if err := p.Access(&r.RowCreate{}); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprint(tr.Calls); s != `[1 1]` {
+		t.Error(s)
+	}
+}
+
+func TestRow_NotFound(t *testing.T) {
+	waits := []time.Duration(nil)
+	tr := &TestingRow{Failures: 1, Err: r.ErrConnectionLost, Then: fmt.Errorf("missing: %w", r.ErrNotFound)}
+	p := Row{tr, TestingPolicy(&waits)}
+	p.Retryable = func(err error) bool { return errors.Is(err, r.ErrConnectionLost) }
+	// The first attempt may have deleted the row.
+	if errs := p.AccessSlice([]r.RowAccess{r.RowDelete{}, &r.RowUpdate{}}); fmt.Sprint(errs) != `[<nil> <nil>]` {
+		t.Error(errs)
+	}
+	if s := fmt.Sprint(tr.Calls); s != `[2 2]` {
+		t.Error(s)
+	}
+	// Without a previous attempt the error is returned.
+	tr.Calls = nil
+	tr.Failures = 0
+	if err := p.Access(r.RowDelete{}); !errors.Is(err, r.ErrNotFound) {
+		t.Error(err)
+	}
+}
+
+// TestingCache is a CacheProvider failing the CacheGets of Failing once.
+type TestingCache struct {
+	cm.Cache
+	Failing c.CacheKey
+	Calls   []int
+}
+
+func (p *TestingCache) AccessSlice(aa []c.CacheAccess) []error {
+	p.Calls = append(p.Calls, len(aa))
+	errs := p.Cache.AccessSlice(aa)
+	for i, a := range aa {
+		if g, ok := a.(*c.CacheGet); ok && g.Key == p.Failing {
+			p.Failing = ""
+			errs[i] = TestingRetryableError{}
+		}
+	}
+	return errs
+}
+
+func TestCache_AccessSlice(t *testing.T) {
+	waits := []time.Duration(nil)
+	tc := &TestingCache{Failing: "bar"}
+	tc.Cache.Access(c.CacheSet{Key: "bar", Value: c.CacheValue("2")})
+	p := Cache{tc, TestingPolicy(&waits)}
+	g := &c.CacheGet{Key: "bar"}
+	errs := p.AccessSlice([]c.CacheAccess{c.CacheSet{Key: "foo", Value: c.CacheValue("1")}, g})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil>]` {
+		t.Error(s)
+	}
+	if string(g.Value) != "2" {
+		t.Error(g.Value)
+	}
+	// Only the failed CacheGet is performed again.
+	if s := fmt.Sprint(tc.Calls); s != `[2 1]` {
+		t.Error(s)
+	}
+}
+
+func TestPolicy_MaxBackoff(t *testing.T) {
+	waits := []time.Duration(nil)
+	p := TestingPolicy(&waits)
+	p.MaxBackoff = 30 * time.Millisecond
+	for attempt := 1; attempt <= 5; attempt++ {
+		p.wait(attempt)
+	}
+	for _, d := range waits[2:] {
+		if d < p.MaxBackoff/2 || d > p.MaxBackoff {
+			t.Error(waits)
+		}
+	}
+}
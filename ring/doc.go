@@ -0,0 +1,9 @@
+/*
+Consistent hashing ring.
+
+A Ring distributes keys among a set of named nodes. Every node owns a number
+of points in the ring proportional to its weight, and a key belongs to the
+node that owns the first point following the hash of the key. Adding or
+removing a node only remaps the keys that belong to that node.
+*/
+package heptane
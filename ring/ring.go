@@ -0,0 +1,101 @@
+package heptane
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of points per unit of weight used when New is
+// given a non positive number of replicas.
+const DefaultReplicas = 160
+
+// Node is a member of a Ring.
+type Node struct {
+	// Name identifies the node, the points of the node in the ring depend
+	// only on its Name and Weight.
+	Name string
+	// Weight is the relative amount of keys that belong to the node. Values
+	// lower than 1 mean 1.
+	Weight int
+}
+
+type point struct {
+	hash uint64
+	node int
+}
+
+// Ring is a consistent hashing ring. Safe to be used from different
+// goroutines.
+type Ring struct {
+	points []point
+}
+
+// New returns a Ring of the given Nodes, each one with replicas points per
+// unit of weight.
+func New(nodes []Node, replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	points := []point(nil)
+	for i, n := range nodes {
+		w := n.Weight
+		if w < 1 {
+			w = 1
+		}
+		for j := 0; j < w*replicas; j += 2 {
+			d := md5.Sum([]byte(n.Name + "-" + strconv.Itoa(j/2)))
+			points = append(points,
+				point{binary.BigEndian.Uint64(d[0:8]), i},
+				point{binary.BigEndian.Uint64(d[8:16]), i})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return points[i].node < points[j].node
+	})
+	return &Ring{points}
+}
+
+// Node returns the index in the slice given to New of the Node the key belongs
+// to, or -1 if the Ring is empty.
+func (r *Ring) Node(key []byte) int {
+	if len(r.points) == 0 {
+		return -1
+	}
+	return r.points[r.search(key)].node
+}
+
+// search returns the position of the first point following the hash of the
+// key. The Ring must not be empty.
+func (r *Ring) search(key []byte) int {
+	d := md5.Sum(key)
+	h := binary.BigEndian.Uint64(d[0:8])
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return i
+}
+
+// Nodes returns the indexes of up to n distinct Nodes for the key: the Node
+// the key belongs to followed by the Nodes of the next points in the Ring.
+func (r *Ring) Nodes(key []byte, n int) []int {
+	if len(r.points) == 0 || n < 1 {
+		return nil
+	}
+	nodes := []int(nil)
+	seen := map[int]bool{}
+	for i, j := r.search(key), 0; j < len(r.points) && len(nodes) < n; i, j = (i+1)%len(r.points), j+1 {
+		if node := r.points[i].node; !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
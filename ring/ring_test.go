@@ -0,0 +1,84 @@
+package heptane
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing_Empty(t *testing.T) {
+	r := New(nil, 0)
+	if n := r.Node([]byte("foo")); n != -1 {
+		t.Error(n)
+	}
+}
+
+func TestRing_Single(t *testing.T) {
+	r := New([]Node{{Name: "a"}}, 0)
+	for i := 0; i < 100; i++ {
+		if n := r.Node([]byte(strconv.Itoa(i))); n != 0 {
+			t.Fatal(n)
+		}
+	}
+}
+
+func TestRing_Deterministic(t *testing.T) {
+	r1 := New([]Node{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 0)
+	r2 := New([]Node{{Name: "c"}, {Name: "a"}, {Name: "b"}}, 0)
+	names1 := []string{"a", "b", "c"}
+	names2 := []string{"c", "a", "b"}
+	for i := 0; i < 1000; i++ {
+		k := []byte(strconv.Itoa(i))
+		if n1, n2 := names1[r1.Node(k)], names2[r2.Node(k)]; n1 != n2 {
+			t.Fatal(i, n1, n2)
+		}
+	}
+}
+
+func TestRing_Balance(t *testing.T) {
+	r := New([]Node{{Name: "a"}, {Name: "b"}, {Name: "c", Weight: 2}}, 0)
+	counts := make([]int, 3)
+	for i := 0; i < 40000; i++ {
+		counts[r.Node([]byte(strconv.Itoa(i)))]++
+	}
+	for i, want := range []int{10000, 10000, 20000} {
+		if c := counts[i]; c < want*8/10 || c > want*12/10 {
+			t.Error(i, c)
+		}
+	}
+}
+
+func TestRing_MinimalRemapping(t *testing.T) {
+	r1 := New([]Node{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 0)
+	r2 := New([]Node{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}, 0)
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		k := []byte(strconv.Itoa(i))
+		n1, n2 := r1.Node(k), r2.Node(k)
+		if n1 != n2 {
+			if n2 != 3 {
+				t.Fatal(i, n1, n2)
+			}
+			moved++
+		}
+	}
+	if moved < 1500 || moved > 3500 {
+		t.Error(moved)
+	}
+}
+
+func TestRing_Nodes(t *testing.T) {
+	r := New([]Node{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 0)
+	if nodes := New(nil, 0).Nodes([]byte("foo"), 2); nodes != nil {
+		t.Error(nodes)
+	}
+	for i := 0; i < 100; i++ {
+		k := []byte(strconv.Itoa(i))
+		nodes := r.Nodes(k, 2)
+		if len(nodes) != 2 || nodes[0] != r.Node(k) || nodes[0] == nodes[1] {
+			t.Fatal(i, nodes)
+		}
+		if nodes := r.Nodes(k, 5); len(nodes) != 3 {
+			t.Fatal(i, nodes)
+		}
+	}
+}
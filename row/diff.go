@@ -0,0 +1,88 @@
+package heptane
+
+import "fmt"
+
+// TableDiff describes the changes between two versions of a Table.
+type TableDiff struct {
+	// AddedValues contains the fields of Values only in the new Table.
+	AddedValues []FieldName
+	// RemovedValues contains the fields of Values only in the old Table.
+	RemovedValues []FieldName
+	// ChangedTypes contains the fields in both Tables whose FieldType
+	// changed.
+	ChangedTypes []FieldName
+	// PartitionKeyChanged means the PartitionKeys differ.
+	PartitionKeyChanged bool
+	// PrimaryKeyChanged means the PrimaryKeys differ.
+	PrimaryKeyChanged bool
+	// CacheFormatChanged means the cache keys or values of the old Table
+	// cannot be read as those of the new Table, because the PrimaryKey, the
+	// Values or their order or FieldTypes changed.
+	CacheFormatChanged bool
+	// CachePrefixRotated means the PrimaryKeyCachePrefixes differ.
+	CachePrefixRotated bool
+}
+
+// Diff returns the changes from the old to the new version of a Table.
+func Diff(old, new Table) TableDiff {
+	d := TableDiff{
+		PartitionKeyChanged: !equalFieldNames(old.PartitionKey, new.PartitionKey),
+		PrimaryKeyChanged:   !equalFieldNames(old.PrimaryKey, new.PrimaryKey),
+		CachePrefixRotated:  fmt.Sprintf("%q", old.PrimaryKeyCachePrefix) != fmt.Sprintf("%q", new.PrimaryKeyCachePrefix),
+	}
+	oldFields := map[FieldName]bool{}
+	for _, fns := range [][]FieldName{old.PrimaryKey, old.Values} {
+		for _, fn := range fns {
+			oldFields[fn] = true
+		}
+	}
+	newFields := map[FieldName]bool{}
+	for _, fns := range [][]FieldName{new.PrimaryKey, new.Values} {
+		for _, fn := range fns {
+			newFields[fn] = true
+			if oldFields[fn] && old.Types[fn] != new.Types[fn] {
+				d.ChangedTypes = append(d.ChangedTypes, fn)
+			}
+		}
+	}
+	for _, fn := range new.Values {
+		if !oldFields[fn] {
+			d.AddedValues = append(d.AddedValues, fn)
+		}
+	}
+	for _, fn := range old.Values {
+		if !newFields[fn] {
+			d.RemovedValues = append(d.RemovedValues, fn)
+		}
+	}
+	d.CacheFormatChanged = d.PrimaryKeyChanged || d.ChangedTypes != nil ||
+		!equalFieldNames(old.Values, new.Values)
+	return d
+}
+
+// equalFieldNames returns whether both slices have the same FieldNames in the
+// same order.
+func equalFieldNames(a, b []FieldName) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compatible returns whether the old Table may be migrated in place to the new
+// one, that is, neither the PartitionKey nor the PrimaryKey changed.
+func (d TableDiff) Compatible() bool {
+	return !d.PartitionKeyChanged && !d.PrimaryKeyChanged
+}
+
+// MustRotateCachePrefix returns whether the PrimaryKeyCachePrefix of the new
+// Table must be changed, so the cache entries written with the old Table are
+// not read with the new one.
+func (d TableDiff) MustRotateCachePrefix() bool {
+	return d.CacheFormatChanged && !d.CachePrefixRotated
+}
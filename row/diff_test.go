@@ -0,0 +1,64 @@
+package heptane
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff_Equal(t *testing.T) {
+	d := Diff(TestingTable(), TestingTable())
+	if s := fmt.Sprintf("%+v", d); s != `{AddedValues:[] RemovedValues:[] ChangedTypes:[] PartitionKeyChanged:false PrimaryKeyChanged:false CacheFormatChanged:false CachePrefixRotated:false}` {
+		t.Error(s)
+	}
+	if !d.Compatible() || d.MustRotateCachePrefix() {
+		t.Error(d)
+	}
+}
+
+func TestDiff_Values(t *testing.T) {
+	old := TestingTable()
+	old.Values = []FieldName{"baz", "qux"}
+	old.Types["qux"] = "string"
+	new := TestingTable()
+	new.Values = []FieldName{"baz", "quux"}
+	new.Types["baz"] = "string"
+	new.Types["quux"] = "bool"
+	d := Diff(old, new)
+	if s := fmt.Sprintf("%+v", d); s != `{AddedValues:[quux] RemovedValues:[qux] ChangedTypes:[baz] PartitionKeyChanged:false PrimaryKeyChanged:false CacheFormatChanged:true CachePrefixRotated:false}` {
+		t.Error(s)
+	}
+	if !d.Compatible() || !d.MustRotateCachePrefix() {
+		t.Error(d)
+	}
+	new.PrimaryKeyCachePrefix = []string{"table_pk", "1"}
+	if d := Diff(old, new); d.MustRotateCachePrefix() {
+		t.Error(d)
+	}
+}
+
+func TestDiff_PrimaryKey(t *testing.T) {
+	new := TestingTable()
+	new.PrimaryKey = []FieldName{"foo", "baz"}
+	new.Values = []FieldName{"bar"}
+	d := Diff(TestingTable(), new)
+	if s := fmt.Sprintf("%+v", d); s != `{AddedValues:[] RemovedValues:[] ChangedTypes:[] PartitionKeyChanged:false PrimaryKeyChanged:true CacheFormatChanged:true CachePrefixRotated:false}` {
+		t.Error(s)
+	}
+	if d.Compatible() || !d.MustRotateCachePrefix() {
+		t.Error(d)
+	}
+}
+
+func TestDiff_FieldNamesWithSpaces(t *testing.T) {
+	old := TestingTable()
+	old.PartitionKey = []FieldName{"a b"}
+	old.PrimaryKey = []FieldName{"a b"}
+	old.Values = []FieldName{"c d"}
+	new := TestingTable()
+	new.PartitionKey = []FieldName{"a", "b"}
+	new.PrimaryKey = []FieldName{"a", "b"}
+	new.Values = []FieldName{"c", "d"}
+	if d := Diff(old, new); !d.PartitionKeyChanged || !d.PrimaryKeyChanged || !d.CacheFormatChanged {
+		t.Error(d)
+	}
+}
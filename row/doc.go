@@ -26,6 +26,9 @@ the types of each field in a map FieldTypesByName (strings and bools),
 the prefix PrimaryKeyCachePrefix in an external cache that contains the
 PrimaryKeys as cache key and the Values as cache values.
 
+Diff compares two versions of a Table, telling whether the table may be
+migrated in place and whether the PrimaryKeyCachePrefix must be changed.
+
 RowAccesses
 
 RowThe type Access is the interface for all operations, and there is one struct
@@ -36,7 +39,7 @@ are optional, only given Values are inserted.
 
 RowRetrieve means a SELECT from the table of all Values. For the WHERE: full
 PartitionKey is mandatory, fields from the PrimaryKey are optional, Values are
-ignored.
+ignored. A Consistent RowRetrieve must observe all the previous writes.
 
 RowUpdate means an UPDATE of the table. Full PrimaryKey is mandatory, Values
 are optional, only given Values are updated.
@@ -0,0 +1,58 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The semantic errors of the RowProviders. An error of a RowProvider meaning
+// a duplicate key, a missing row, a timeout or a lost connection matches one of
+// them with errors.Is, whatever the RowProvider.
+var (
+	ErrDuplicateKey   = errors.New("Duplicate Key")
+	ErrNotFound       = errors.New("Not Found")
+	ErrTimeout        = errors.New("Timeout")
+	ErrConnectionLost = errors.New("Connection Lost")
+)
+
+// The reasons of the TableValidationErrors.
+var (
+	ErrEmptyTableName            = errors.New("Empty TableName in Table")
+	ErrMissingPartitionKey       = errors.New("Missing PartitionKey")
+	ErrEmptyPartitionKeyField    = errors.New("Empty FieldName in PartitionKey")
+	ErrRepeatedPartitionKeyField = errors.New("Repeated FieldName in PartitionKey")
+	ErrMissingPrimaryKey         = errors.New("Missing PrimaryKey")
+	ErrEmptyPrimaryKeyField      = errors.New("Empty FieldName in PrimaryKey")
+	ErrMismatchedPrimaryKey      = errors.New("Mismatched PrimaryKey")
+	ErrRepeatedPrimaryKeyField   = errors.New("Repeated FieldName in PrimaryKey")
+	ErrEmptyValuesField          = errors.New("Empty FieldName in Values")
+	ErrRepeatedValuesField       = errors.New("Repeated FieldName in Values")
+	ErrMissingFieldType          = errors.New("Missing FieldType for FieldName")
+	ErrInvalidFieldType          = errors.New("Invalid FieldType for FieldName")
+)
+
+// TableValidationError is produced when the definition of a Table is
+// inconsistent. Reason is one of the Err variables of this package, FieldName
+// and FieldType are the offending field and type, if any.
+type TableValidationError struct {
+	TableName TableName
+	FieldName FieldName
+	FieldType FieldType
+	Reason    error
+}
+
+func (e TableValidationError) Error() string {
+	switch {
+	case e.Reason == ErrEmptyTableName:
+		return e.Reason.Error()
+	case e.Reason == ErrInvalidFieldType:
+		return fmt.Sprintf("Table %v: %v %v: %v", e.TableName, e.Reason, e.FieldName, e.FieldType)
+	case e.FieldName != "":
+		return fmt.Sprintf("Table %v: %v: %v", e.TableName, e.Reason, e.FieldName)
+	}
+	return fmt.Sprintf("Table %v: %v", e.TableName, e.Reason)
+}
+
+func (e TableValidationError) Unwrap() error {
+	return e.Reason
+}
@@ -65,6 +65,10 @@ type RowRetrieve struct {
 	// FieldValues will contain one or more rows, each one with all its
 	// fields.
 	RetrievedValues []FieldValuesByName
+	// Consistent requires the retrieved rows to reflect all the previous
+	// writes, e.g. a RowProvider with read replicas must read from the
+	// primary database.
+	Consistent bool
 }
 
 // RowUpdate specifies the update of a row in a table.
@@ -91,3 +95,31 @@ type RowProvider interface {
 	// AccessSlice performs several acccesses to the table.
 	AccessSlice([]RowAccess) []error
 }
+
+// PartitionScanner is the interface of the RowProviders that are able to
+// enumerate the partitions of a table. It is optional, development tools like
+// migrations rely on it to find all the rows of a table.
+type PartitionScanner interface {
+	// ScanPartitions returns the PartitionKey of every partition of the
+	// table that contains at least one row.
+	ScanPartitions(Table) ([]FieldValuesByName, error)
+}
+
+// TableVerifier is the interface of the RowProviders able to verify the
+// specification of a table against their backend. It is optional, tables are
+// verified when they are registered.
+type TableVerifier interface {
+	// VerifyTable returns an error describing the differences between the
+	// specification of the table and the table in the backend, if any.
+	VerifyTable(Table) error
+}
+
+// TableInvalidator is the interface of the RowProviders that keep state
+// derived from the specification of a table, like prepared statements. It is
+// optional, the state is discarded when the table is registered or
+// unregistered.
+type TableInvalidator interface {
+	// InvalidateTable discards the state derived from the specification of
+	// the table.
+	InvalidateTable(TableName)
+}
@@ -0,0 +1,13 @@
+/*
+In-memory implementation of RowProvider.
+
+Row stores the rows of every table in memory in PrimaryKey order, with the
+semantics of a relational database: a RowCreate fails with an error matching
+ErrDuplicateKey if the row exists, a RowUpdate writes only the given Values of
+an existing row, a RowRetrieve returns the rows of a partition matching the
+given fields of the PrimaryKey and a RowDelete removes a row if it exists. Like
+in the Row of row/sql, a RowUpdate or RowDelete of a missing row succeeds
+without effect. It is safe to use from different
+goroutines and intended for unit tests and local development.
+*/
+package heptane
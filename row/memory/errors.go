@@ -0,0 +1,33 @@
+package heptane
+
+import (
+	"fmt"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// UnsupportedRowAccessTypeError is produced when the type of a RowAccess is
+// not supported. Current supported types are RowCreate, RowRetrieve, RowUpdate
+// and RowDelete.
+type UnsupportedRowAccessTypeError struct {
+	RowAccess r.RowAccess
+}
+
+func (e UnsupportedRowAccessTypeError) Error() string {
+	return fmt.Sprintf("Unsupported RowAccess Type: %#v", e.RowAccess)
+}
+
+// DuplicatePrimaryKeyError is produced when a RowCreate is performed on a row
+// that already exists. It matches ErrDuplicateKey of row with errors.Is.
+type DuplicatePrimaryKeyError struct {
+	TableName  r.TableName
+	PrimaryKey r.FieldValuesByName
+}
+
+func (e DuplicatePrimaryKeyError) Error() string {
+	return fmt.Sprintf("Duplicate PrimaryKey in Table %v: %v", e.TableName, e.PrimaryKey)
+}
+
+func (e DuplicatePrimaryKeyError) Is(target error) bool {
+	return target == r.ErrDuplicateKey
+}
@@ -0,0 +1,279 @@
+package heptane
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	h "github.com/heptanes/heptane"
+	r "github.com/heptanes/heptane/row"
+)
+
+// Row implements RowProvider and PartitionScanner. The zero value is an empty
+// database.
+type Row struct {
+	m      sync.RWMutex
+	tables map[r.TableName][]r.FieldValuesByName
+}
+
+// rank orders the types of FieldValues: nulls, bools, strings and any other.
+func rank(v r.FieldValue) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 2
+	}
+	return 3
+}
+
+// compare returns -1, 0 or 1 as the FieldValue a is lower than, equal to or
+// greater than b. Nulls are lower than any other value and false is lower than
+// true. Values of different types, like the rows stored with a previous
+// version of the Table, are ordered by their types.
+func compare(a, b r.FieldValue) int {
+	if ra, rb := rank(a), rank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch a := a.(type) {
+	case bool:
+		b, _ := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		}
+		return 1
+	case string:
+		b, _ := b.(string)
+		switch {
+		case a == b:
+			return 0
+		case a < b:
+			return -1
+		}
+		return 1
+	case nil:
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%#v", a), fmt.Sprintf("%#v", b))
+}
+
+// compareKeys compares two rows by the given fields, in order.
+func compareKeys(fns []r.FieldName, a, b r.FieldValuesByName) int {
+	for _, fn := range fns {
+		if c := compare(a[fn], b[fn]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// check verifies the types of the given FieldValues and that the given fields
+// are present.
+func check(t r.Table, fvn r.FieldValuesByName, mandatory []r.FieldName) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	for _, fn := range mandatory {
+		if _, ok := fvn[fn]; !ok {
+			return h.MissingFieldValueError{TableName: t.Name, FieldName: fn, FieldValuesByName: fvn}
+		}
+	}
+	for _, fns := range [][]r.FieldName{t.PrimaryKey, t.Values} {
+		for _, fn := range fns {
+			fv := fvn[fn]
+			ok := fv == nil
+			switch t.Types[fn] {
+			case "bool":
+				_, ok = fv.(bool)
+			case "string":
+				_, ok = fv.(string)
+			}
+			if fv != nil && !ok {
+				return h.UnsupportedFieldValueError{FieldType: t.Types[fn], FieldValue: fv}
+			}
+		}
+	}
+	return nil
+}
+
+// find returns the position of the row with the PrimaryKey of fvn, or where it
+// would be inserted, and whether it exists.
+func (p *Row) find(t r.Table, fvn r.FieldValuesByName) (int, bool) {
+	rows := p.tables[t.Name]
+	i := sort.Search(len(rows), func(i int) bool {
+		return compareKeys(t.PrimaryKey, rows[i], fvn) >= 0
+	})
+	return i, i < len(rows) && compareKeys(t.PrimaryKey, rows[i], fvn) == 0
+}
+
+func (p *Row) Create(a r.RowCreate) error {
+	if err := check(a.Table, a.FieldValues, a.Table.PrimaryKey); err != nil {
+		return err
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	i, ok := p.find(a.Table, a.FieldValues)
+	if ok {
+		pk := r.FieldValuesByName{}
+		for _, fn := range a.Table.PrimaryKey {
+			pk[fn] = a.FieldValues[fn]
+		}
+		return DuplicatePrimaryKeyError{a.Table.Name, pk}
+	}
+	row := make(r.FieldValuesByName, len(a.Table.PrimaryKey)+len(a.Table.Values))
+	for _, fns := range [][]r.FieldName{a.Table.PrimaryKey, a.Table.Values} {
+		for _, fn := range fns {
+			row[fn] = a.FieldValues[fn]
+		}
+	}
+	if p.tables == nil {
+		p.tables = map[r.TableName][]r.FieldValuesByName{}
+	}
+	rows := append(p.tables[a.Table.Name], nil)
+	copy(rows[i+1:], rows[i:])
+	rows[i] = row
+	p.tables[a.Table.Name] = rows
+	return nil
+}
+
+func (p *Row) Retrieve(a *r.RowRetrieve) error {
+	if err := check(a.Table, a.FieldValues, a.Table.PartitionKey); err != nil {
+		return err
+	}
+	// The rows matching the longest given prefix of the PrimaryKey are
+	// contiguous.
+	prefix := a.Table.PrimaryKey
+	for i, fn := range prefix {
+		if _, ok := a.FieldValues[fn]; !ok {
+			prefix = prefix[:i]
+			break
+		}
+	}
+	p.m.RLock()
+	defer p.m.RUnlock()
+	rows := p.tables[a.Table.Name]
+	i := sort.Search(len(rows), func(i int) bool {
+		return compareKeys(prefix, rows[i], a.FieldValues) >= 0
+	})
+	a.RetrievedValues = nil
+	for ; i < len(rows) && compareKeys(prefix, rows[i], a.FieldValues) == 0; i++ {
+		match := true
+		for _, fn := range a.Table.PrimaryKey[len(prefix):] {
+			if fv, ok := a.FieldValues[fn]; ok && compare(fv, rows[i][fn]) != 0 {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		fvn := make(r.FieldValuesByName, len(rows[i]))
+		for fn, fv := range rows[i] {
+			fvn[fn] = fv
+		}
+		a.RetrievedValues = append(a.RetrievedValues, fvn)
+	}
+	return nil
+}
+
+func (p *Row) Update(a r.RowUpdate) error {
+	if err := check(a.Table, a.FieldValues, a.Table.PrimaryKey); err != nil {
+		return err
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	i, ok := p.find(a.Table, a.FieldValues)
+	if !ok {
+		return nil
+	}
+	// Rows are replaced, not modified, so the retrieved rows are never
+	// changed.
+	row := make(r.FieldValuesByName, len(a.Table.PrimaryKey)+len(a.Table.Values))
+	for fn, fv := range p.tables[a.Table.Name][i] {
+		row[fn] = fv
+	}
+	for _, fn := range a.Table.Values {
+		if fv, ok := a.FieldValues[fn]; ok {
+			row[fn] = fv
+		}
+	}
+	p.tables[a.Table.Name][i] = row
+	return nil
+}
+
+func (p *Row) Delete(a r.RowDelete) error {
+	if err := check(a.Table, a.FieldValues, a.Table.PrimaryKey); err != nil {
+		return err
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	i, ok := p.find(a.Table, a.FieldValues)
+	if !ok {
+		return nil
+	}
+	rows := p.tables[a.Table.Name]
+	copy(rows[i:], rows[i+1:])
+	rows[len(rows)-1] = nil
+	p.tables[a.Table.Name] = rows[:len(rows)-1]
+	return nil
+}
+
+// Access implements RowProvider.
+func (p *Row) Access(a r.RowAccess) error {
+	switch a := a.(type) {
+	case r.RowCreate:
+		return p.Create(a)
+	case *r.RowCreate:
+		return p.Create(*a)
+	case *r.RowRetrieve:
+		return p.Retrieve(a)
+	case r.RowUpdate:
+		return p.Update(a)
+	case *r.RowUpdate:
+		return p.Update(*a)
+	case r.RowDelete:
+		return p.Delete(a)
+	case *r.RowDelete:
+		return p.Delete(*a)
+	}
+	return UnsupportedRowAccessTypeError{a}
+}
+
+// AccessSlice implements RowProvider.
+func (p *Row) AccessSlice(aa []r.RowAccess) (errs []error) {
+	for _, a := range aa {
+		errs = append(errs, p.Access(a))
+	}
+	return
+}
+
+// ScanPartitions implements PartitionScanner. The partitions are returned in
+// PrimaryKey order.
+func (p *Row) ScanPartitions(t r.Table) ([]r.FieldValuesByName, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	p.m.RLock()
+	defer p.m.RUnlock()
+	fvns := []r.FieldValuesByName(nil)
+	for i, row := range p.tables[t.Name] {
+		if i > 0 && compareKeys(t.PartitionKey, p.tables[t.Name][i-1], row) == 0 {
+			continue
+		}
+		fvn := make(r.FieldValuesByName, len(t.PartitionKey))
+		for _, fn := range t.PartitionKey {
+			fvn[fn] = row[fn]
+		}
+		fvns = append(fvns, fvn)
+	}
+	return fvns, nil
+}
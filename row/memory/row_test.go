@@ -0,0 +1,237 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestingTable1() r.Table {
+	return r.Table{
+		Name:                  "table1",
+		PartitionKey:          []r.FieldName{"foo"},
+		PrimaryKey:            []r.FieldName{"foo", "bar"},
+		Values:                []r.FieldName{"baz", "qux"},
+		Types:                 r.FieldTypesByName{"foo": "string", "bar": "string", "baz": "string", "qux": "bool"},
+		PrimaryKeyCachePrefix: []string{"table1_pk", "0"},
+	}
+}
+
+func TestingRow(t *testing.T) *Row {
+	p := &Row{}
+	b := TestingTable1()
+	for _, fvn := range []r.FieldValuesByName{
+		{"foo": "2", "bar": "1", "baz": "a", "qux": true},
+		{"foo": "1", "bar": "3", "baz": "b"},
+		{"foo": "1", "bar": "2", "baz": "c", "qux": false},
+		{"foo": "3", "bar": "1"},
+	} {
+		if err := p.Access(r.RowCreate{Table: b, FieldValues: fvn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestRetrieve_ChangedTypes(t *testing.T) {
+	p := TestingRow(t)
+	// A new version of the Table whose field bar is a bool.
+	b := TestingTable1()
+	b.Types["bar"] = "bool"
+	if err := p.Access(r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": true}}); err != nil {
+		t.Error(err)
+	}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	}
+	// The bools are lower than the strings.
+	if s := fmt.Sprint(a.RetrievedValues); s != `[map[bar:true baz:<nil> foo:1 qux:<nil>] map[bar:2 baz:c foo:1 qux:false] map[bar:3 baz:b foo:1 qux:<nil>]]` {
+		t.Error(s)
+	}
+	if c := compare(1, 2); c != -1 {
+		t.Error(c)
+	}
+}
+
+func TestCreate_Duplicate(t *testing.T) {
+	p := TestingRow(t)
+	a := r.RowCreate{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "d"}}
+	if err := p.Access(&a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Duplicate PrimaryKey in Table table1: map[bar:2 foo:1]` {
+		t.Error(s)
+	} else if !errors.Is(err, r.ErrDuplicateKey) {
+		t.Error(err)
+	}
+}
+
+func TestCreate_MissingPrimaryKey(t *testing.T) {
+	p := &Row{}
+	a := r.RowCreate{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "baz": "d"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Missing FieldValue for Field table1.bar: map[baz:d foo:1]` {
+		t.Error(s)
+	}
+}
+
+func TestCreate_UnsupportedFieldValue(t *testing.T) {
+	p := &Row{}
+	a := r.RowCreate{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "qux": "true"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported FieldValue for FieldType bool: true` {
+		t.Error(s)
+	}
+}
+
+func TestCreate_ValidationError(t *testing.T) {
+	p := &Row{}
+	b := TestingTable1()
+	b.Name = ""
+	if err := p.Access(r.RowCreate{Table: b}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Empty TableName in Table` {
+		t.Error(s)
+	}
+}
+
+func TestRetrieve_PartitionKey(t *testing.T) {
+	p := TestingRow(t)
+	a := &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", a.RetrievedValues); s != `[map[bar:2 baz:c foo:1 qux:false] map[bar:3 baz:b foo:1 qux:<nil>]]` {
+		t.Error(s)
+	}
+}
+
+func TestRetrieve_PrimaryKey(t *testing.T) {
+	p := TestingRow(t)
+	a := &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "3"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", a.RetrievedValues); s != `[map[bar:3 baz:b foo:1 qux:<nil>]]` {
+		t.Error(s)
+	}
+	a = &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "4"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if a.RetrievedValues != nil {
+		t.Error(a.RetrievedValues)
+	}
+}
+
+func TestRetrieve_MissingPartitionKey(t *testing.T) {
+	p := TestingRow(t)
+	a := &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"bar": "1"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Missing FieldValue for Field table1.foo: map[bar:1]` {
+		t.Error(s)
+	}
+}
+
+func TestRetrieve_Copy(t *testing.T) {
+	p := TestingRow(t)
+	a := &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "3"}}
+	if err := p.Access(a); err != nil {
+		t.Fatal(err)
+	}
+	a.RetrievedValues[0]["baz"] = "changed"
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", a.RetrievedValues); s != `[map[bar:1 baz:<nil> foo:3 qux:<nil>]]` {
+		t.Error(s)
+	}
+}
+
+func TestUpdate_Partial(t *testing.T) {
+	p := TestingRow(t)
+	b := TestingTable1()
+	if err := p.Access(r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "2", "bar": "1", "baz": "d"}}); err != nil {
+		t.Error(err)
+	}
+	if err := p.Access(&r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "2", "bar": "9", "baz": "e"}}); err != nil {
+		t.Error(err)
+	}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "2"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", a.RetrievedValues); s != `[map[bar:1 baz:d foo:2 qux:true]]` {
+		t.Error(s)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	p := TestingRow(t)
+	b := TestingTable1()
+	for _, fvn := range []r.FieldValuesByName{{"foo": "1", "bar": "2"}, {"foo": "1", "bar": "9"}} {
+		if err := p.Access(r.RowDelete{Table: b, FieldValues: fvn}); err != nil {
+			t.Error(err)
+		}
+	}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", a.RetrievedValues); s != `[map[bar:3 baz:b foo:1 qux:<nil>]]` {
+		t.Error(s)
+	}
+}
+
+func TestAccess_Unsupported(t *testing.T) {
+	p := &Row{}
+	if err := p.Access(r.RowRetrieve{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported RowAccess Type: heptane.RowRetrieve{Table:heptane.Table{Name:"", PartitionKey:[]heptane.FieldName(nil), PrimaryKey:[]heptane.FieldName(nil), Values:[]heptane.FieldName(nil), Types:heptane.FieldTypesByName(nil), PrimaryKeyCachePrefix:[]string(nil)}, FieldValues:heptane.FieldValuesByName(nil), RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false}` {
+		t.Error(s)
+	}
+}
+
+func TestScanPartitions(t *testing.T) {
+	p := TestingRow(t)
+	if fvns, err := p.ScanPartitions(TestingTable1()); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%v", fvns); s != `[map[foo:1] map[foo:2] map[foo:3]]` {
+		t.Error(s)
+	}
+}
+
+func TestAccessSlice_Concurrent(t *testing.T) {
+	p := &Row{}
+	b := TestingTable1()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				fvn := r.FieldValuesByName{"foo": fmt.Sprint(j % 5), "bar": fmt.Sprint(i, "-", j)}
+				for _, err := range p.AccessSlice([]r.RowAccess{
+					r.RowCreate{Table: b, FieldValues: fvn},
+					&r.RowRetrieve{Table: b, FieldValues: fvn},
+				}) {
+					if err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if fvns, err := p.ScanPartitions(b); err != nil {
+		t.Error(err)
+	} else if l := len(fvns); l != 5 {
+		t.Error(l)
+	}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "0"}}
+	if err := p.Access(a); err != nil {
+		t.Error(err)
+	} else if l := len(a.RetrievedValues); l != 80 {
+		t.Error(l)
+	}
+}
@@ -0,0 +1,103 @@
+package heptane
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balancer selects the read replica that performs a query.
+type Balancer interface {
+	// Select returns the index of a replica, lower than n.
+	Select(n int) int
+	// Observe records the duration and the error of a query performed by
+	// the replica i.
+	Observe(i int, d time.Duration, err error)
+}
+
+// RoundRobin implements Balancer. Replicas are selected in turns.
+type RoundRobin struct {
+	next uint64
+}
+
+// Select implements Balancer.
+func (b *RoundRobin) Select(n int) int {
+	return int((atomic.AddUint64(&b.next, 1) - 1) % uint64(n))
+}
+
+// Observe implements Balancer.
+func (b *RoundRobin) Observe(i int, d time.Duration, err error) {
+}
+
+// LeastLatency implements Balancer. The replica with the lowest moving average
+// of the duration of its queries is selected. Replicas without observations
+// are selected first. A failed query is observed with its duration plus
+// Penalty. Every Explore selections the replicas are selected in turns
+// instead, so the slow replicas are observed again when they recover.
+type LeastLatency struct {
+	// Weight is the weight of a new observation in the moving average,
+	// between 0 and 1. Zero means 0.2.
+	Weight float64
+	// Penalty is added to the duration of a failed query. Zero means one
+	// second.
+	Penalty time.Duration
+	// Explore is the period of the selections in turns. Zero means 100,
+	// negative means never.
+	Explore int
+
+	m       sync.Mutex
+	average []float64
+	count   int
+	next    int
+}
+
+// Select implements Balancer.
+func (b *LeastLatency) Select(n int) int {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.grow(n)
+	e := b.Explore
+	if e == 0 {
+		e = 100
+	}
+	if b.count++; e > 0 && b.count%e == 0 {
+		b.next = (b.next + 1) % n
+		return b.next
+	}
+	s := 0
+	for i := 0; i < n; i++ {
+		if b.average[i] < b.average[s] {
+			s = i
+		}
+	}
+	return s
+}
+
+// Observe implements Balancer.
+func (b *LeastLatency) Observe(i int, d time.Duration, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.grow(i + 1)
+	if err != nil {
+		p := b.Penalty
+		if p <= 0 {
+			p = time.Second
+		}
+		d += p
+	}
+	w := b.Weight
+	if w <= 0 || w > 1 {
+		w = 0.2
+	}
+	if b.average[i] == 0 {
+		b.average[i] = float64(d)
+	} else {
+		b.average[i] = w*float64(d) + (1-w)*b.average[i]
+	}
+}
+
+func (b *LeastLatency) grow(n int) {
+	for len(b.average) < n {
+		b.average = append(b.average, 0)
+	}
+}
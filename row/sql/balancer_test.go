@@ -0,0 +1,64 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRoundRobin(t *testing.T) {
+	b := &RoundRobin{}
+	s := []int{}
+	for i := 0; i < 5; i++ {
+		s = append(s, b.Select(3))
+	}
+	if s := fmt.Sprint(s); s != `[0 1 2 0 1]` {
+		t.Error(s)
+	}
+}
+
+func TestLeastLatency_Unobserved(t *testing.T) {
+	b := &LeastLatency{}
+	b.Observe(0, time.Second, nil)
+	if i := b.Select(2); i != 1 {
+		t.Error(i)
+	}
+}
+
+func TestLeastLatency_Observed(t *testing.T) {
+	b := &LeastLatency{}
+	b.Observe(0, 3*time.Millisecond, nil)
+	b.Observe(1, 2*time.Millisecond, nil)
+	b.Observe(2, 4*time.Millisecond, nil)
+	if i := b.Select(3); i != 1 {
+		t.Error(i)
+	}
+	b.Observe(1, 100*time.Millisecond, nil)
+	if i := b.Select(3); i != 0 {
+		t.Error(i)
+	}
+}
+
+func TestLeastLatency_Failed(t *testing.T) {
+	b := &LeastLatency{}
+	b.Observe(0, time.Millisecond, errors.New("failed"))
+	b.Observe(1, 100*time.Millisecond, nil)
+	if i := b.Select(2); i != 1 {
+		t.Error(i)
+	}
+}
+
+func TestLeastLatency_Explore(t *testing.T) {
+	b := &LeastLatency{Explore: 3}
+	b.Observe(0, time.Millisecond, nil)
+	b.Observe(1, time.Second, nil)
+	b.Observe(2, time.Second, nil)
+	s := []int{}
+	for i := 0; i < 9; i++ {
+		s = append(s, b.Select(3))
+	}
+	if s := fmt.Sprint(s); s != `[0 0 1 0 0 2 0 0 0]` {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,307 @@
+package heptane
+
+import (
+	"strconv"
+	"strings"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// AccessSlice implements RowProvider. When BatchSize is greater than one,
+// consecutive compatible RowAccesses are coalesced in a single statement:
+// RowCreates of the same fields in a multi-row INSERT, RowRetrieves and
+// RowDeletes by the same non null fields of the PrimaryKey in a SELECT or a
+// DELETE with an IN list, or with the condition of an InDialect. When a
+// coalesced INSERT fails, its RowCreates are performed one by one so each error
+// is reported for its RowCreate. When a coalesced SELECT or DELETE fails, its
+// error is reported for each of its RowAccesses.
+//
+// The retrieved rows are matched to their RowRetrieves by the encoding of the
+// values of their keys, so a collation comparing strings without regard to
+// case or accents may select rows that are not returned to any RowRetrieve.
+// Such tables should not be used with a BatchSize greater than one.
+func (p *Row) AccessSlice(aa []r.RowAccess) (errs []error) {
+	if p.Transaction != nil {
+		return p.AccessSliceTx(aa, p.Transaction)
+	}
+	errs = make([]error, len(aa))
+	for i := 0; i < len(aa); {
+		j := i + 1
+		if k := p.batchKey(aa[i]); k != "" {
+			for j < len(aa) && j-i < p.BatchSize && p.batchKey(aa[j]) == k {
+				j++
+			}
+		}
+		if j-i == 1 {
+			errs[i] = p.Access(aa[i])
+		} else {
+			p.batch(aa[i:j], errs[i:j])
+		}
+		i = j
+	}
+	return
+}
+
+// batchKey returns the key shared by the RowAccesses that may be coalesced with
+// the given one, or an empty string if it may not be coalesced.
+func (p *Row) batchKey(a r.RowAccess) string {
+	if p.BatchSize < 2 {
+		return ""
+	}
+	switch a := a.(type) {
+	case r.RowCreate:
+		if p.Upsert || a.Table.Validate() != nil {
+			return ""
+		}
+		return signature('c', a.Table, a.FieldValues)
+	case *r.RowCreate:
+		return p.batchKey(*a)
+	case *r.RowRetrieve:
+		if a.Table.Validate() != nil || !nonNullKey(a.Table, a.FieldValues, false) {
+			return ""
+		}
+		if a.Consistent {
+			return signature('R', a.Table, a.FieldValues)
+		}
+		return signature('r', a.Table, a.FieldValues)
+	case r.RowDelete:
+		if a.Table.Validate() != nil || !nonNullKey(a.Table, a.FieldValues, true) {
+			return ""
+		}
+		return signature('d', a.Table, primaryKey(a.Table, a.FieldValues))
+	case *r.RowDelete:
+		return p.batchKey(*a)
+	}
+	return ""
+}
+
+// nonNullKey returns whether some fields of the PrimaryKey, or all of them if
+// full, are present and none of the present ones is null.
+func nonNullKey(b r.Table, fvn r.FieldValuesByName, full bool) bool {
+	n := 0
+	for _, fn := range b.PrimaryKey {
+		fv, ok := fvn[fn]
+		if !ok {
+			if full {
+				return false
+			}
+			continue
+		}
+		if fv == nil {
+			return false
+		}
+		n++
+	}
+	return n > 0
+}
+
+// batch performs several compatible RowAccesses, as selected by batchKey.
+func (p *Row) batch(aa []r.RowAccess, errs []error) {
+	switch aa[0].(type) {
+	case r.RowCreate, *r.RowCreate:
+		rcs := make([]r.RowCreate, len(aa))
+		for i, a := range aa {
+			if rc, ok := a.(*r.RowCreate); ok {
+				rcs[i] = *rc
+			} else {
+				rcs[i] = a.(r.RowCreate)
+			}
+		}
+		p.createBatch(rcs, errs)
+	case *r.RowRetrieve:
+		rrs := make([]*r.RowRetrieve, len(aa))
+		for i, a := range aa {
+			rrs[i] = a.(*r.RowRetrieve)
+		}
+		p.retrieveBatch(rrs, errs)
+	case r.RowDelete, *r.RowDelete:
+		rds := make([]r.RowDelete, len(aa))
+		for i, a := range aa {
+			if rd, ok := a.(*r.RowDelete); ok {
+				rds[i] = *rd
+			} else {
+				rds[i] = a.(r.RowDelete)
+			}
+		}
+		p.deleteBatch(rds, errs)
+	}
+}
+
+func (p *Row) createBatch(rcs []r.RowCreate, errs []error) {
+	b := rcs[0].Table
+	fns := make([]r.FieldName, 0, len(b.PrimaryKey)+len(b.Values))
+	fns = append(fns, b.PrimaryKey...)
+	for _, fn := range b.Values {
+		if _, ok := rcs[0].FieldValues[fn]; ok {
+			fns = append(fns, fn)
+		}
+	}
+	args := make([]interface{}, 0, len(rcs)*len(fns))
+	for _, rc := range rcs {
+		for _, fn := range fns {
+			args = append(args, rc.FieldValues[fn])
+		}
+	}
+	sig := signature('c', b, rcs[0].FieldValues) + "#" + strconv.Itoa(len(rcs))
+	err := p.exec(nil, b.Name, sig, func() string {
+		sb := &strings.Builder{}
+		sb.WriteString("INSERT INTO ")
+		p.Dialect.WriteTableName(sb, b.Name)
+		sb.WriteString(" (")
+		for i, fn := range fns {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			p.Dialect.WriteFieldName(sb, fn)
+		}
+		sb.WriteString(") VALUES ")
+		n := 0
+		for i := range rcs {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(")
+			for j := range fns {
+				if j != 0 {
+					sb.WriteString(", ")
+				}
+				p.Dialect.WritePlaceholder(sb, n)
+				n++
+			}
+			sb.WriteString(")")
+		}
+		return sb.String()
+	}, args...)
+	if err != nil {
+		for i, rc := range rcs {
+			errs[i] = p.Create(rc)
+		}
+	}
+}
+
+// writeIn writes a condition comparing the given fields to n lists of values.
+// The placeholders are numbered from 0.
+func (p *Row) writeIn(sb *strings.Builder, fns []r.FieldName, n int) {
+	if d, ok := p.Dialect.(InDialect); ok && len(fns) > 1 {
+		d.WriteIn(sb, fns, n)
+		return
+	}
+	if len(fns) > 1 {
+		sb.WriteString("(")
+	}
+	for i, fn := range fns {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		p.Dialect.WriteFieldName(sb, fn)
+	}
+	if len(fns) > 1 {
+		sb.WriteString(")")
+	}
+	sb.WriteString(" IN (")
+	k := 0
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		if len(fns) > 1 {
+			sb.WriteString("(")
+		}
+		for j := range fns {
+			if j != 0 {
+				sb.WriteString(", ")
+			}
+			p.Dialect.WritePlaceholder(sb, k)
+			k++
+		}
+		if len(fns) > 1 {
+			sb.WriteString(")")
+		}
+	}
+	sb.WriteString(")")
+}
+
+// keyFields returns the fields of the PrimaryKey that are present.
+func keyFields(b r.Table, fvn r.FieldValuesByName) []r.FieldName {
+	kfns := make([]r.FieldName, 0, len(b.PrimaryKey))
+	for _, fn := range b.PrimaryKey {
+		if _, ok := fvn[fn]; ok {
+			kfns = append(kfns, fn)
+		}
+	}
+	return kfns
+}
+
+func (p *Row) retrieveBatch(rrs []*r.RowRetrieve, errs []error) {
+	b := rrs[0].Table
+	kfns := keyFields(b, rrs[0].FieldValues)
+	fns := make([]r.FieldName, 0, len(b.PrimaryKey)+len(b.Values))
+	fns = append(fns, kfns...)
+	for _, fn := range b.PrimaryKey {
+		if _, ok := rrs[0].FieldValues[fn]; !ok {
+			fns = append(fns, fn)
+		}
+	}
+	fns = append(fns, b.Values...)
+	args := make([]interface{}, 0, len(rrs)*len(kfns))
+	byKey := map[string][]*r.RowRetrieve{}
+	for _, rr := range rrs {
+		for _, fn := range kfns {
+			args = append(args, rr.FieldValues[fn])
+		}
+		k := string(b.Encode(kfns, rr.FieldValues))
+		byKey[k] = append(byKey[k], rr)
+	}
+	sig := signature('r', b, rrs[0].FieldValues) + "#" + strconv.Itoa(len(rrs))
+	db, done := p.reader(nil, rrs[0].Consistent)
+	fvns, err := p.query(db, nil, b, fns, nil, sig, func() string {
+		sb := &strings.Builder{}
+		sb.WriteString("SELECT ")
+		for i, fn := range fns {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			p.Dialect.WriteFieldName(sb, fn)
+		}
+		sb.WriteString(" FROM ")
+		p.Dialect.WriteTableName(sb, b.Name)
+		sb.WriteString(" WHERE ")
+		p.writeIn(sb, kfns, len(rrs))
+		return sb.String()
+	}, args...)
+	done(err)
+	if err != nil {
+		for i := range rrs {
+			errs[i] = err
+		}
+		return
+	}
+	for _, rr := range rrs {
+		rr.RetrievedValues = nil
+	}
+	for _, fvn := range fvns {
+		for _, rr := range byKey[string(b.Encode(kfns, fvn))] {
+			rr.RetrievedValues = append(rr.RetrievedValues, fvn)
+		}
+	}
+}
+
+func (p *Row) deleteBatch(rds []r.RowDelete, errs []error) {
+	b := rds[0].Table
+	args := make([]interface{}, 0, len(rds)*len(b.PrimaryKey))
+	for _, rd := range rds {
+		args = keyArgs(args, b, rd.FieldValues)
+	}
+	sig := signature('d', b, primaryKey(b, rds[0].FieldValues)) + "#" + strconv.Itoa(len(rds))
+	err := p.exec(nil, b.Name, sig, func() string {
+		sb := &strings.Builder{}
+		sb.WriteString("DELETE FROM ")
+		p.Dialect.WriteTableName(sb, b.Name)
+		sb.WriteString(" WHERE ")
+		p.writeIn(sb, b.PrimaryKey, len(rds))
+		return sb.String()
+	}, args...)
+	for i := range rds {
+		errs[i] = err
+	}
+}
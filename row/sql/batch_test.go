@@ -0,0 +1,232 @@
+package heptane
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestAccessSlice_Create(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar', 'baz'\) VALUES \(\?, \?, \?\), \(\?, \?, \?\)`).
+		WithArgs("1", "2", "3", "4", "5", "6").
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar', 'baz'\) VALUES \(\?, \?, \?\)`).
+		WithArgs("7", "8", "9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("10", "11").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 2}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}},
+		&r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "4", "bar": "5", "baz": "6"}},
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "7", "bar": "8", "baz": "9"}},
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "10", "bar": "11"}},
+	})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Create_Error(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\), \(\?, \?\)`).
+		WithArgs("1", "2", "1", "3").
+		WillReturnError(errors.New("problem"))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnError(errors.New("duplicate"))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "3"}},
+	})
+	if s := fmt.Sprint(errs); s != `[Sql Error: duplicate <nil>]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Retrieve_PrimaryKey(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT 'foo', 'bar', 'baz' FROM 'table1' WHERE \('foo', 'bar'\) IN \(\(\?, \?\), \(\?, \?\)\)`).
+		WithArgs("1", "2", "4", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"foo", "bar", "baz"}).AddRow("4", "5", "6"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	a1 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	a2 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "4", "bar": "5"}}
+	if s := fmt.Sprint(rp.AccessSlice([]r.RowAccess{a1, a2})); s != `[<nil> <nil>]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%#v", a1.RetrievedValues); s != `[]heptane.FieldValuesByName(nil)` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%#v", a2.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"5", "baz":"6", "foo":"4"}}` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Retrieve_PartitionKey(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT 'foo', 'bar', 'baz' FROM 'table1' WHERE 'foo' IN \(\?, \?\)`).
+		WithArgs("1", "4").
+		WillReturnRows(sqlmock.NewRows([]string{"foo", "bar", "baz"}).
+			AddRow("1", "2", "3").AddRow("4", "5", "6").AddRow("1", "7", "8"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	a1 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
+	a2 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "4"}}
+	if s := fmt.Sprint(rp.AccessSlice([]r.RowAccess{a1, a2})); s != `[<nil> <nil>]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%#v", a1.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1"}, heptane.FieldValuesByName{"bar":"7", "baz":"8", "foo":"1"}}` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%#v", a2.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"5", "baz":"6", "foo":"4"}}` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Retrieve_Error(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT 'foo', 'bar', 'baz' FROM 'table1' WHERE 'foo' IN \(\?, \?\)`).
+		WithArgs("1", "4").
+		WillReturnError(errors.New("problem"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	a1 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
+	a2 := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "4"}}
+	// The RowRetrieves are not performed again one by one.
+	if s := fmt.Sprint(rp.AccessSlice([]r.RowAccess{a1, a2})); s != `[Sql Error: problem Sql Error: problem]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Delete(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`DELETE FROM 'table1' WHERE \('foo', 'bar'\) IN \(\(\?, \?\), \(\?, \?\)\)`).
+		WithArgs("1", "2", "4", "5").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' IS NULL`).
+		WithArgs("7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		&r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "4", "bar": "5", "baz": "6"}},
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "7", "bar": nil}},
+	})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Delete_SQLServer(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`DELETE FROM \[table1\] WHERE \(\[foo\] = @p1 AND \[bar\] = @p2\) OR \(\[foo\] = @p3 AND \[bar\] = @p4\)`).
+		WithArgs("1", "2", "4", "5").
+		WillReturnError(errors.New("problem"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: SQLServer{}, BatchSize: 10}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "4", "bar": "5"}},
+	})
+	if s := fmt.Sprint(errs); s != `[Sql Error: problem Sql Error: problem]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSlice_Mixed(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, BatchSize: 10}
+	a1 := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	a2 := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if s := fmt.Sprint(rp.AccessSlice([]r.RowAccess{a1, a2, a1})); s != `[<nil> <nil> <nil>]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
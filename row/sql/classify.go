@@ -0,0 +1,66 @@
+package heptane
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// The semantic errors of the database, aliases of those of row. A SqlError
+// caused by one of them matches it with errors.Is.
+var (
+	ErrDuplicateKey   = r.ErrDuplicateKey
+	ErrNotFound       = r.ErrNotFound
+	ErrTimeout        = r.ErrTimeout
+	ErrConnectionLost = r.ErrConnectionLost
+)
+
+// Is reports whether the error of the database is the given semantic error.
+func (e SqlError) Is(target error) bool {
+	return target != nil && semantic(e.Err) == target
+}
+
+// semantic maps an error of the database to one of the semantic errors, or
+// nil if there is none.
+func semantic(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrConnectionLost
+	}
+	if ne := net.Error(nil); errors.As(err, &ne) {
+		if ne.Timeout() {
+			return ErrTimeout
+		}
+		return ErrConnectionLost
+	}
+	if state, ok := sqlState(err); ok {
+		switch {
+		case state == "23505": // unique violation
+			return ErrDuplicateKey
+		case state == "57014", state == "55P03": // query canceled, lock not available
+			return ErrTimeout
+		case strings.HasPrefix(state, "08"), state == "57P01": // connection exception, admin shutdown
+			return ErrConnectionLost
+		}
+		return nil
+	}
+	if n, ok := number(err); ok {
+		switch n {
+		case 1062, 2601, 2627: // duplicate entry in MySQL, duplicate key in SQL Server
+			return ErrDuplicateKey
+		case 1222, 3024: // lock request timeout in SQL Server, query timeout in MySQL
+			return ErrTimeout
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package heptane
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSqlError_Is(t *testing.T) {
+	for _, c := range []struct {
+		err    error
+		target error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{driver.ErrBadConn, ErrConnectionLost},
+		{TestingPostgresError{"23505"}, ErrDuplicateKey},
+		{TestingPostgresError{"57014"}, ErrTimeout},
+		{TestingPostgresError{"08006"}, ErrConnectionLost},
+		{&TestingMySQLError{1062}, ErrDuplicateKey},
+		{&TestingMySQLError{3024}, ErrTimeout},
+		{fmt.Errorf("wrapped: %w", &TestingMySQLError{1062}), ErrDuplicateKey},
+		{errors.New("problem"), nil},
+	} {
+		err := error(SqlError{c.err})
+		for _, target := range []error{ErrDuplicateKey, ErrNotFound, ErrTimeout, ErrConnectionLost} {
+			if errors.Is(err, target) != (target == c.target) {
+				t.Error(c.err, target)
+			}
+		}
+		if !errors.Is(err, c.err) {
+			t.Error(c.err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package heptane
+
+import (
+	"strconv"
+	"strings"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// UpsertDialect is the interface of the Dialects able to generate upserts.
+type UpsertDialect interface {
+	Dialect
+
+	// WriteUpsert writes to the Builder a statement inserting a row with the
+	// given PrimaryKey and Values fields or, if a row with the same
+	// PrimaryKey exists, updating its Values fields. The placeholders of the
+	// fields are numbered from 0, PrimaryKey first.
+	WriteUpsert(sb *strings.Builder, n r.TableName, pk, values []r.FieldName)
+}
+
+// InDialect is the interface of the Dialects without row value constructors,
+// which compare several fields to lists of values in another way.
+type InDialect interface {
+	Dialect
+
+	// WriteIn writes to the Builder a condition comparing the given fields
+	// to n lists of values. The placeholders are numbered from 0.
+	WriteIn(sb *strings.Builder, fns []r.FieldName, n int)
+}
+
+func writeFieldNames(sb *strings.Builder, d Dialect, prefix string, fns []r.FieldName) {
+	for i, fn := range fns {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(prefix)
+		d.WriteFieldName(sb, fn)
+	}
+}
+
+func writePlaceholders(sb *strings.Builder, d Dialect, n int) {
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		d.WritePlaceholder(sb, i)
+	}
+}
+
+// writeInsert writes the common part of the upserts of Postgres and MySQL.
+func writeInsert(sb *strings.Builder, d Dialect, n r.TableName, fns []r.FieldName) {
+	sb.WriteString("INSERT INTO ")
+	d.WriteTableName(sb, n)
+	sb.WriteString(" (")
+	writeFieldNames(sb, d, "", fns)
+	sb.WriteString(") VALUES (")
+	writePlaceholders(sb, d, len(fns))
+	sb.WriteString(")")
+}
+
+// Postgres implements UpsertDialect for PostgreSQL.
+type Postgres struct{}
+
+func (d Postgres) WriteTableName(sb *strings.Builder, n r.TableName) {
+	sb.WriteString(`"`)
+	sb.WriteString(strings.Replace(string(n), `"`, `""`, -1))
+	sb.WriteString(`"`)
+}
+
+func (d Postgres) WriteFieldName(sb *strings.Builder, n r.FieldName) {
+	sb.WriteString(`"`)
+	sb.WriteString(strings.Replace(string(n), `"`, `""`, -1))
+	sb.WriteString(`"`)
+}
+
+func (d Postgres) WritePlaceholder(sb *strings.Builder, i int) {
+	sb.WriteString("$")
+	sb.WriteString(strconv.Itoa(i + 1))
+}
+
+// WriteUpsert writes an INSERT ... ON CONFLICT statement.
+func (d Postgres) WriteUpsert(sb *strings.Builder, n r.TableName, pk, values []r.FieldName) {
+	writeInsert(sb, d, n, append(append([]r.FieldName{}, pk...), values...))
+	sb.WriteString(" ON CONFLICT (")
+	writeFieldNames(sb, d, "", pk)
+	if len(values) == 0 {
+		sb.WriteString(") DO NOTHING")
+		return
+	}
+	sb.WriteString(") DO UPDATE SET ")
+	for i, fn := range values {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		d.WriteFieldName(sb, fn)
+		sb.WriteString(" = EXCLUDED.")
+		d.WriteFieldName(sb, fn)
+	}
+}
+
+// MySQL implements UpsertDialect for MySQL.
+type MySQL struct{}
+
+func (d MySQL) WriteTableName(sb *strings.Builder, n r.TableName) {
+	sb.WriteString("`")
+	sb.WriteString(strings.Replace(string(n), "`", "``", -1))
+	sb.WriteString("`")
+}
+
+func (d MySQL) WriteFieldName(sb *strings.Builder, n r.FieldName) {
+	sb.WriteString("`")
+	sb.WriteString(strings.Replace(string(n), "`", "``", -1))
+	sb.WriteString("`")
+}
+
+func (d MySQL) WritePlaceholder(sb *strings.Builder, i int) {
+	sb.WriteString("?")
+}
+
+// WriteUpsert writes an INSERT ... ON DUPLICATE KEY UPDATE statement.
+func (d MySQL) WriteUpsert(sb *strings.Builder, n r.TableName, pk, values []r.FieldName) {
+	writeInsert(sb, d, n, append(append([]r.FieldName{}, pk...), values...))
+	sb.WriteString(" ON DUPLICATE KEY UPDATE ")
+	if len(values) == 0 {
+		// Updating a field to itself leaves the row untouched.
+		d.WriteFieldName(sb, pk[0])
+		sb.WriteString(" = ")
+		d.WriteFieldName(sb, pk[0])
+		return
+	}
+	for i, fn := range values {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		d.WriteFieldName(sb, fn)
+		sb.WriteString(" = VALUES(")
+		d.WriteFieldName(sb, fn)
+		sb.WriteString(")")
+	}
+}
+
+// SQLServer implements UpsertDialect and InDialect for Microsoft SQL Server.
+type SQLServer struct{}
+
+func (d SQLServer) WriteTableName(sb *strings.Builder, n r.TableName) {
+	sb.WriteString("[")
+	sb.WriteString(strings.Replace(string(n), "]", "]]", -1))
+	sb.WriteString("]")
+}
+
+func (d SQLServer) WriteFieldName(sb *strings.Builder, n r.FieldName) {
+	sb.WriteString("[")
+	sb.WriteString(strings.Replace(string(n), "]", "]]", -1))
+	sb.WriteString("]")
+}
+
+func (d SQLServer) WritePlaceholder(sb *strings.Builder, i int) {
+	sb.WriteString("@p")
+	sb.WriteString(strconv.Itoa(i + 1))
+}
+
+// WriteIn writes a disjunction of conjunctions of equalities, since SQL Server
+// does not support row values in IN.
+func (d SQLServer) WriteIn(sb *strings.Builder, fns []r.FieldName, n int) {
+	k := 0
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			sb.WriteString(" OR ")
+		}
+		sb.WriteString("(")
+		for j, fn := range fns {
+			if j != 0 {
+				sb.WriteString(" AND ")
+			}
+			d.WriteFieldName(sb, fn)
+			sb.WriteString(" = ")
+			d.WritePlaceholder(sb, k)
+			k++
+		}
+		sb.WriteString(")")
+	}
+}
+
+// WriteUpsert writes a MERGE statement.
+func (d SQLServer) WriteUpsert(sb *strings.Builder, n r.TableName, pk, values []r.FieldName) {
+	fns := append(append([]r.FieldName{}, pk...), values...)
+	sb.WriteString("MERGE INTO ")
+	d.WriteTableName(sb, n)
+	sb.WriteString(" AS t USING (VALUES (")
+	writePlaceholders(sb, d, len(fns))
+	sb.WriteString(")) AS s (")
+	writeFieldNames(sb, d, "", fns)
+	sb.WriteString(") ON ")
+	for i, fn := range pk {
+		if i != 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString("t.")
+		d.WriteFieldName(sb, fn)
+		sb.WriteString(" = s.")
+		d.WriteFieldName(sb, fn)
+	}
+	if len(values) != 0 {
+		sb.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for i, fn := range values {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("t.")
+			d.WriteFieldName(sb, fn)
+			sb.WriteString(" = s.")
+			d.WriteFieldName(sb, fn)
+		}
+	}
+	sb.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	writeFieldNames(sb, d, "", fns)
+	sb.WriteString(") VALUES (")
+	writeFieldNames(sb, d, "s.", fns)
+	sb.WriteString(");")
+}
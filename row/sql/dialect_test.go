@@ -0,0 +1,88 @@
+package heptane
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestUpsertDialect_WriteUpsert(t *testing.T) {
+	for _, c := range []struct {
+		d      UpsertDialect
+		values []r.FieldName
+		s      string
+	}{
+		{Postgres{}, []r.FieldName{"baz", "qux"}, `INSERT INTO "table1" ("foo", "bar", "baz", "qux") VALUES ($1, $2, $3, $4) ON CONFLICT ("foo", "bar") DO UPDATE SET "baz" = EXCLUDED."baz", "qux" = EXCLUDED."qux"`},
+		{Postgres{}, nil, `INSERT INTO "table1" ("foo", "bar") VALUES ($1, $2) ON CONFLICT ("foo", "bar") DO NOTHING`},
+		{MySQL{}, []r.FieldName{"baz", "qux"}, "INSERT INTO `table1` (`foo`, `bar`, `baz`, `qux`) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE `baz` = VALUES(`baz`), `qux` = VALUES(`qux`)"},
+		{MySQL{}, nil, "INSERT INTO `table1` (`foo`, `bar`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `foo` = `foo`"},
+		{SQLServer{}, []r.FieldName{"baz", "qux"}, `MERGE INTO [table1] AS t USING (VALUES (@p1, @p2, @p3, @p4)) AS s ([foo], [bar], [baz], [qux]) ON t.[foo] = s.[foo] AND t.[bar] = s.[bar] WHEN MATCHED THEN UPDATE SET t.[baz] = s.[baz], t.[qux] = s.[qux] WHEN NOT MATCHED THEN INSERT ([foo], [bar], [baz], [qux]) VALUES (s.[foo], s.[bar], s.[baz], s.[qux]);`},
+		{SQLServer{}, nil, `MERGE INTO [table1] AS t USING (VALUES (@p1, @p2)) AS s ([foo], [bar]) ON t.[foo] = s.[foo] AND t.[bar] = s.[bar] WHEN NOT MATCHED THEN INSERT ([foo], [bar]) VALUES (s.[foo], s.[bar]);`},
+	} {
+		sb := &strings.Builder{}
+		c.d.WriteUpsert(sb, "table1", []r.FieldName{"foo", "bar"}, c.values)
+		if s := sb.String(); s != c.s {
+			t.Error(s)
+		}
+	}
+}
+
+func TestDialect_Quote(t *testing.T) {
+	for _, c := range []struct {
+		d Dialect
+		n r.FieldName
+		s string
+	}{
+		{Postgres{}, `a"b`, `"a""b"`},
+		{MySQL{}, "a`b", "`a``b`"},
+		{SQLServer{}, `a]b`, `[a]]b]`},
+	} {
+		sb := &strings.Builder{}
+		c.d.WriteFieldName(sb, c.n)
+		if s := sb.String(); s != c.s {
+			t.Error(s)
+		}
+	}
+}
+
+func TestCreate_Upsert(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`INSERT INTO "table1" \("foo", "bar", "baz"\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \("foo", "bar"\) DO UPDATE SET "baz" = EXCLUDED."baz"`).
+		WithArgs("1", "2", "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "table1" \("foo", "bar"\) VALUES \(\$1, \$2\) ON CONFLICT \("foo", "bar"\) DO NOTHING`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: Postgres{}, Upsert: true, BatchSize: 10}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}},
+		r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdate_UnsupportedUpsert(t *testing.T) {
+	b := TestingTable1()
+	rp := Row{Dialect: TestDialect{}, Upsert: true}
+	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := rp.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported Upsert in Dialect heptane.TestDialect` {
+		t.Error(s)
+	}
+}
@@ -1,4 +1,34 @@
 /*
 Implementation of RowProvider relying on database/sql.
+
+Row performs every write on a primary sql.DB and every RowRetrieve on one of
+its read replicas, selected by a Balancer, unless the RowRetrieve is
+Consistent. When Statements is not zero, Row caches the sql string of each
+kind of RowAccess and keeps it prepared on every database. When BatchSize is
+greater than one, AccessSlice coalesces consecutive compatible RowAccesses in
+multi-row statements, comparing the keys with row values in IN unless the
+Dialect is an InDialect such as SQLServer. AccessSliceTx, or AccessSlice when Transaction is not
+nil, performs all its RowAccesses in a single transaction. When Upsert is
+true, RowCreates and RowUpdates overwrite the existing rows like Cassandra
+does, with the upsert statement of an UpsertDialect such as Postgres, MySQL or
+SQLServer.
+
+The SqlErrors caused by transient conditions of the database, like deadlocks,
+lock timeouts, serialization failures or lost connections, report true from
+their method Retryable, so the RowAccesses may be retried by the Row in retry.
+The SqlErrors meaning a duplicate key, a missing row, a timeout or a lost
+connection match ErrDuplicateKey, ErrNotFound, ErrTimeout or ErrConnectionLost
+of row, aliased in this package, with errors.Is, with the drivers of Postgres,
+MySQL and SQL Server.
+
+VerifyTable compares a Table with the table in the database, if VerifyTables
+is set. Plan generates the
+statements migrating a table between two versions of its Table, which may be
+obtained from the database with ReadTable.
+
+ShardedRow distributes the partitions of the tables over several Rows,
+selecting the shard of each RowAccess from its PartitionKey. A Resharding
+moves the partitions of a ShardedRow to a new set of shards while it is in
+use.
 */
 package heptane
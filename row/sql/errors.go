@@ -2,6 +2,7 @@ package heptane
 
 import (
 	"fmt"
+	"strings"
 
 	r "github.com/heptanes/heptane/row"
 )
@@ -25,3 +26,88 @@ type SqlError struct {
 func (e SqlError) Error() string {
 	return fmt.Sprintf("Sql Error: %v", e.Err)
 }
+
+func (e SqlError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedUpsertError is produced when a Row performs upserts with a
+// Dialect that does not implement UpsertDialect.
+type UnsupportedUpsertError struct {
+	Dialect Dialect
+}
+
+func (e UnsupportedUpsertError) Error() string {
+	return fmt.Sprintf("Unsupported Upsert in Dialect %T", e.Dialect)
+}
+
+// SchemaMismatchError is produced when a Table does not match the table in the
+// database. Differences describes each mismatch.
+type SchemaMismatchError struct {
+	TableName   r.TableName
+	Differences []string
+}
+
+func (e SchemaMismatchError) Error() string {
+	return fmt.Sprintf("Schema Mismatch in Table %v: %v", e.TableName, strings.Join(e.Differences, ", "))
+}
+
+// IncompatibleTableChangeError is produced when a Table cannot be migrated in
+// place because its PartitionKey or PrimaryKey changed.
+type IncompatibleTableChangeError struct {
+	TableName r.TableName
+}
+
+func (e IncompatibleTableChangeError) Error() string {
+	return fmt.Sprintf("Incompatible Change of Table %v: PartitionKey or PrimaryKey changed", e.TableName)
+}
+
+// IncompletePartitionKeyError is produced when a RowAccess to a ShardedRow
+// does not contain the full PartitionKey, so the shard cannot be selected.
+type IncompletePartitionKeyError struct {
+	TableName r.TableName
+	FieldName r.FieldName
+}
+
+func (e IncompletePartitionKeyError) Error() string {
+	return fmt.Sprintf("Incomplete PartitionKey for Table %v: Missing FieldValue for Field %v", e.TableName, e.FieldName)
+}
+
+// NoShardError is produced when a RowAccess is performed on a ShardedRow
+// without Shards.
+type NoShardError struct{}
+
+func (e NoShardError) Error() string {
+	return "No Shard in ShardedRow"
+}
+
+// InvalidShardError is produced when a Router returns a shard out of the range
+// of the Shards, e.g. a RingRouter whose Ring does not match the Shards.
+type InvalidShardError struct {
+	Shard  int
+	Shards int
+}
+
+func (e InvalidShardError) Error() string {
+	return fmt.Sprintf("Invalid Shard %v of %v Shards", e.Shard, e.Shards)
+}
+
+// IncompleteReshardingError is produced when a Resharding is flipped before
+// all its partitions have been moved.
+type IncompleteReshardingError struct {
+	Progress ReshardingProgress
+}
+
+func (e IncompleteReshardingError) Error() string {
+	return fmt.Sprintf("Incomplete Resharding: %v of %v partitions moved, %v dirty", e.Progress.Moved, e.Progress.Total, e.Progress.Dirty)
+}
+
+// RolledBackError is produced for the RowAccesses of a transaction rolled back
+// because of the error of the RowAccess at position Index.
+type RolledBackError struct {
+	Index int
+}
+
+func (e RolledBackError) Error() string {
+	return fmt.Sprintf("Transaction rolled back by the error of RowAccess %v", e.Index)
+}
@@ -0,0 +1,224 @@
+package heptane
+
+import (
+	"strings"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// DDLDialect is the interface of the Dialects able to generate statements
+// changing the schema of the database.
+type DDLDialect interface {
+	Dialect
+
+	// WriteAddColumn writes to the Builder a statement adding a column to a
+	// table.
+	WriteAddColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType)
+	// WriteDropColumn writes to the Builder a statement removing a column
+	// from a table.
+	WriteDropColumn(sb *strings.Builder, n r.TableName, fn r.FieldName)
+	// WriteAlterColumn writes to the Builder a statement changing the type
+	// of a column of a table.
+	WriteAlterColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType)
+}
+
+// MigrationPlan contains the changes between two versions of a Table and the
+// statements migrating the table in the database from the old to the new one.
+type MigrationPlan struct {
+	Diff       r.TableDiff
+	Statements []string
+}
+
+// Plan compares two versions of a Table and returns the statements to migrate
+// the table from the old to the new one. It fails if the change is not
+// Compatible, the Diff of the MigrationPlan is set anyway.
+func Plan(d DDLDialect, old, new r.Table) (MigrationPlan, error) {
+	p := MigrationPlan{Diff: r.Diff(old, new)}
+	if err := new.Validate(); err != nil {
+		return p, err
+	}
+	if !p.Diff.Compatible() {
+		return p, IncompatibleTableChangeError{new.Name}
+	}
+	for _, fn := range p.Diff.AddedValues {
+		sb := &strings.Builder{}
+		d.WriteAddColumn(sb, new.Name, fn, new.Types[fn])
+		p.Statements = append(p.Statements, sb.String())
+	}
+	for _, fn := range p.Diff.ChangedTypes {
+		sb := &strings.Builder{}
+		d.WriteAlterColumn(sb, new.Name, fn, new.Types[fn])
+		p.Statements = append(p.Statements, sb.String())
+	}
+	for _, fn := range p.Diff.RemovedValues {
+		sb := &strings.Builder{}
+		d.WriteDropColumn(sb, new.Name, fn)
+		p.Statements = append(p.Statements, sb.String())
+	}
+	return p, nil
+}
+
+// ReadTable returns the Table found in the database with the Name of the given
+// Table, to be compared with it. The PartitionKey and PrimaryKeyCachePrefix
+// are those of the given Table, and so is the PrimaryKey unless the Dialect
+// implements SchemaDialect. The other columns are the Values, with the
+// FieldType they are compatible with, if any. The Values of the given Table
+// found in the database come first and in its order, so Diff does not report a
+// change of the cache format because of the order of the columns.
+func (p *Row) ReadTable(b r.Table) (r.Table, error) {
+	live := r.Table{
+		Name:                  b.Name,
+		PartitionKey:          b.PartitionKey,
+		PrimaryKey:            b.PrimaryKey,
+		Types:                 r.FieldTypesByName{},
+		PrimaryKeyCachePrefix: b.PrimaryKeyCachePrefix,
+	}
+	if d, ok := p.Dialect.(SchemaDialect); ok {
+		pk, err := p.primaryKey(d, b.Name)
+		if err != nil {
+			return live, err
+		}
+		live.PrimaryKey = pk
+	}
+	cts, err := p.columnTypes(b.Name)
+	if err != nil {
+		return live, err
+	}
+	pk := map[r.FieldName]bool{}
+	for _, fn := range live.PrimaryKey {
+		pk[fn] = true
+	}
+	columns := map[r.FieldName]bool{}
+	for _, ct := range cts {
+		fn := r.FieldName(ct.Name())
+		columns[fn] = true
+		for _, ft := range []r.FieldType{"string", "bool"} {
+			if compatibleType(ft, ct.DatabaseTypeName()) {
+				live.Types[fn] = ft
+				break
+			}
+		}
+	}
+	values := map[r.FieldName]bool{}
+	for _, fn := range b.Values {
+		if columns[fn] && !pk[fn] {
+			values[fn] = true
+			live.Values = append(live.Values, fn)
+		}
+	}
+	for _, ct := range cts {
+		if fn := r.FieldName(ct.Name()); !pk[fn] && !values[fn] {
+			live.Values = append(live.Values, fn)
+		}
+	}
+	return live, nil
+}
+
+func writeAlterTable(sb *strings.Builder, d Dialect, n r.TableName) {
+	sb.WriteString("ALTER TABLE ")
+	d.WriteTableName(sb, n)
+	sb.WriteString(" ")
+}
+
+func (d Postgres) writeColumnType(sb *strings.Builder, ft r.FieldType) {
+	if ft == "bool" {
+		sb.WriteString("BOOLEAN")
+	} else {
+		sb.WriteString("TEXT")
+	}
+}
+
+// WriteAddColumn implements DDLDialect.
+func (d Postgres) WriteAddColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("ADD COLUMN ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" ")
+	d.writeColumnType(sb, ft)
+}
+
+// WriteDropColumn implements DDLDialect.
+func (d Postgres) WriteDropColumn(sb *strings.Builder, n r.TableName, fn r.FieldName) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("DROP COLUMN ")
+	d.WriteFieldName(sb, fn)
+}
+
+// WriteAlterColumn implements DDLDialect.
+func (d Postgres) WriteAlterColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("ALTER COLUMN ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" TYPE ")
+	d.writeColumnType(sb, ft)
+	sb.WriteString(" USING ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString("::")
+	d.writeColumnType(sb, ft)
+}
+
+func (d MySQL) writeColumnType(sb *strings.Builder, ft r.FieldType) {
+	if ft == "bool" {
+		sb.WriteString("BOOLEAN")
+	} else {
+		sb.WriteString("VARCHAR(255)")
+	}
+}
+
+// WriteAddColumn implements DDLDialect.
+func (d MySQL) WriteAddColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("ADD COLUMN ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" ")
+	d.writeColumnType(sb, ft)
+}
+
+// WriteDropColumn implements DDLDialect.
+func (d MySQL) WriteDropColumn(sb *strings.Builder, n r.TableName, fn r.FieldName) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("DROP COLUMN ")
+	d.WriteFieldName(sb, fn)
+}
+
+// WriteAlterColumn implements DDLDialect.
+func (d MySQL) WriteAlterColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("MODIFY COLUMN ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" ")
+	d.writeColumnType(sb, ft)
+}
+
+func (d SQLServer) writeColumnType(sb *strings.Builder, ft r.FieldType) {
+	if ft == "bool" {
+		sb.WriteString("BIT")
+	} else {
+		sb.WriteString("NVARCHAR(450)")
+	}
+}
+
+// WriteAddColumn implements DDLDialect.
+func (d SQLServer) WriteAddColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("ADD ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" ")
+	d.writeColumnType(sb, ft)
+}
+
+// WriteDropColumn implements DDLDialect.
+func (d SQLServer) WriteDropColumn(sb *strings.Builder, n r.TableName, fn r.FieldName) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("DROP COLUMN ")
+	d.WriteFieldName(sb, fn)
+}
+
+// WriteAlterColumn implements DDLDialect.
+func (d SQLServer) WriteAlterColumn(sb *strings.Builder, n r.TableName, fn r.FieldName, ft r.FieldType) {
+	writeAlterTable(sb, d, n)
+	sb.WriteString("ALTER COLUMN ")
+	d.WriteFieldName(sb, fn)
+	sb.WriteString(" ")
+	d.writeColumnType(sb, ft)
+}
@@ -0,0 +1,117 @@
+package heptane
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestingTable2() r.Table {
+	b := TestingTable1()
+	b.Values = []r.FieldName{"qux", "baz"}
+	b.Types["baz"] = "bool"
+	b.Types["qux"] = "bool"
+	b.PrimaryKeyCachePrefix = []string{"table1_pk", "1"}
+	return b
+}
+
+func TestPlan(t *testing.T) {
+	old := TestingTable1()
+	old.Values = []r.FieldName{"baz", "quux"}
+	old.Types["quux"] = "string"
+	for _, c := range []struct {
+		d DDLDialect
+		s string
+	}{
+		{Postgres{}, `ALTER TABLE "table1" ADD COLUMN "qux" BOOLEAN; ALTER TABLE "table1" ALTER COLUMN "baz" TYPE BOOLEAN USING "baz"::BOOLEAN; ALTER TABLE "table1" DROP COLUMN "quux"`},
+		{MySQL{}, "ALTER TABLE `table1` ADD COLUMN `qux` BOOLEAN; ALTER TABLE `table1` MODIFY COLUMN `baz` BOOLEAN; ALTER TABLE `table1` DROP COLUMN `quux`"},
+		{SQLServer{}, `ALTER TABLE [table1] ADD [qux] BIT; ALTER TABLE [table1] ALTER COLUMN [baz] BIT; ALTER TABLE [table1] DROP COLUMN [quux]`},
+	} {
+		if p, err := Plan(c.d, old, TestingTable2()); err != nil {
+			t.Error(err)
+		} else if s := strings.Join(p.Statements, "; "); s != c.s {
+			t.Error(s)
+		} else if !p.Diff.CacheFormatChanged || p.Diff.MustRotateCachePrefix() {
+			t.Error(p.Diff)
+		}
+	}
+}
+
+func TestPlan_Incompatible(t *testing.T) {
+	b := TestingTable1()
+	b.PartitionKey = []r.FieldName{"foo", "bar"}
+	if p, err := Plan(Postgres{}, TestingTable1(), b); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Incompatible Change of Table table1: PartitionKey or PrimaryKey changed` {
+		t.Error(s)
+	} else if !p.Diff.PartitionKeyChanged || p.Statements != nil {
+		t.Error(p)
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT kcu.column_name FROM information_schema.table_constraints .*`).
+		WithArgs("table1").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("foo").AddRow("bar"))
+	mock.ExpectQuery(`SELECT \* FROM "table1" WHERE 1 = 0`).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("foo").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("bar").OfType("TEXT", ""),
+			sqlmock.NewColumn("baz").OfType("TEXT", ""),
+			sqlmock.NewColumn("quux").OfType("INT4", "")))
+	rp := Row{DB: db, Dialect: Postgres{}}
+	live, err := rp.ReadTable(TestingTable2())
+	if err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%#v", live); s != `heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz", "quux"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "1"}}` {
+		t.Error(s)
+	}
+	if p, err := Plan(Postgres{}, live, TestingTable2()); err != nil {
+		t.Error(err)
+	} else if s := strings.Join(p.Statements, "; "); s != `ALTER TABLE "table1" ADD COLUMN "qux" BOOLEAN; ALTER TABLE "table1" ALTER COLUMN "baz" TYPE BOOLEAN USING "baz"::BOOLEAN; ALTER TABLE "table1" DROP COLUMN "quux"` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadTable_ColumnOrder(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT \* FROM 'table1' WHERE 1 = 0`).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("qux").OfType("TEXT", ""),
+			sqlmock.NewColumn("foo").OfType("TEXT", ""),
+			sqlmock.NewColumn("bar").OfType("TEXT", ""),
+			sqlmock.NewColumn("baz").OfType("TEXT", "")))
+	b := TestingTable1()
+	b.Values = []r.FieldName{"baz", "qux"}
+	b.Types["qux"] = "string"
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	live, err := rp.ReadTable(b)
+	if err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprint(live.Values); s != `[baz qux]` {
+		t.Error(s)
+	}
+	if d := r.Diff(live, b); d.CacheFormatChanged {
+		t.Error(d)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,439 @@
+package heptane
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	h "github.com/heptanes/heptane"
+	mg "github.com/heptanes/heptane/migration"
+	r "github.com/heptanes/heptane/row"
+)
+
+// ReshardingProgress reports the state of a Resharding.
+type ReshardingProgress struct {
+	// Moved is the number of partitions copied and verified.
+	Moved int
+	// Total is the number of partitions that must be moved.
+	Total int
+	// Dirty is the number of moved partitions that must be copied again
+	// because a write to their new shard failed.
+	Dirty int
+}
+
+type movingPartition struct {
+	table       r.Table
+	fvn         r.FieldValuesByName
+	source      *Row
+	destination *Row
+}
+
+// Resharding moves the partitions of the Tables of a ShardedRow to new Shards
+// selected by a new Router while the ShardedRow is in use:
+//
+// Start makes the ShardedRow write every RowCreate, RowUpdate and RowDelete of
+// a moving partition to both its current and its new shard. The writes of an
+// AccessSlice are grouped by shard as usual, the new shards receive theirs in
+// another AccessSlice, and transaction, after the current shards.
+//
+// Run copies the moving partitions to their new shard and verifies them. It may
+// be called again to resume after an error.
+//
+// Flip atomically makes the ShardedRow use the new Shards and Router once every
+// moving partition has been copied.
+//
+// Pause and Resume suspend and continue Run, Abort discards the Resharding
+// without changing the ShardedRow.
+type Resharding struct {
+	// Row is the ShardedRow to reshard.
+	Row *ShardedRow
+	// Shards contains the Row of each new shard. It may share Rows with the
+	// current shards, the partitions whose shard does not change are not
+	// moved.
+	Shards []*Row
+	// Router selects the new shard of each partition. A nil Router means a
+	// ModuloRouter.
+	Router Router
+	// Tables contains the specification of the tables to move.
+	Tables []r.Table
+	// Parallelism is the number of partitions moved concurrently. Values
+	// lower than 1 mean 1.
+	Parallelism int
+	// BatchSize is the maximum number of RowAccesses sent to a new shard in
+	// a single AccessSlice. Values lower than 1 mean 1.
+	BatchSize int
+	// OnProgress, if not nil, is called after every moved partition. Calls
+	// are never concurrent.
+	OnProgress func(ReshardingProgress)
+
+	m       sync.Mutex
+	resumed *sync.Cond
+	paused  bool
+	scanned bool
+	pending map[string]movingPartition
+	moved   map[string]bool
+	dirty   map[string]movingPartition
+	locks   [256]sync.RWMutex
+}
+
+func partitionID(t r.Table, fvn r.FieldValuesByName) string {
+	return string(t.Name) + "#" + mg.PartitionID(t, fvn)
+}
+
+// lock returns the lock of a partition. Writes to the partition hold it for
+// reading and copies of the partition hold it for writing.
+func (p *Resharding) lock(id string) *sync.RWMutex {
+	return &p.locks[p.lockIndex(id)]
+}
+
+func (p *Resharding) lockIndex(id string) int {
+	f := fnv.New32a()
+	f.Write([]byte(id))
+	return int(f.Sum32() % uint32(len(p.locks)))
+}
+
+// Start makes the ShardedRow write the moving partitions to both shards. It
+// waits for the RowAccesses in progress to finish.
+func (p *Resharding) Start() {
+	p.m.Lock()
+	if p.resumed == nil {
+		p.resumed = sync.NewCond(&p.m)
+		p.pending = map[string]movingPartition{}
+		p.moved = map[string]bool{}
+		p.dirty = map[string]movingPartition{}
+	}
+	p.m.Unlock()
+	p.Row.m.Lock()
+	defer p.Row.m.Unlock()
+	p.Row.resharding = p
+}
+
+// Abort makes the ShardedRow stop writing the moving partitions to their new
+// shards.
+func (p *Resharding) Abort() {
+	p.Row.m.Lock()
+	defer p.Row.m.Unlock()
+	if p.Row.resharding == p {
+		p.Row.resharding = nil
+	}
+}
+
+// Pause makes Run wait before moving the next partition.
+func (p *Resharding) Pause() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.paused = true
+}
+
+// Resume makes a paused Run continue.
+func (p *Resharding) Resume() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.paused = false
+	if p.resumed != nil {
+		p.resumed.Broadcast()
+	}
+}
+
+// Progress returns the current state of the Resharding.
+func (p *Resharding) Progress() ReshardingProgress {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.progress()
+}
+
+func (p *Resharding) progress() ReshardingProgress {
+	return ReshardingProgress{Moved: len(p.moved), Total: len(p.pending), Dirty: len(p.dirty)}
+}
+
+// scan finds the moving partitions of every Table. Called with the lock of the
+// ShardedRow held for reading.
+func (p *Resharding) scan() error {
+	for _, t := range p.Tables {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		fvns, err := scanPartitions(p.Row.Shards, t)
+		if err != nil {
+			return err
+		}
+		for _, fvn := range fvns {
+			i, err := route(p.Row.Shards, p.Row.Router, t, fvn)
+			if err != nil {
+				return err
+			}
+			j, err := route(p.Shards, p.Router, t, fvn)
+			if err != nil {
+				return err
+			}
+			if p.Row.Shards[i] != p.Shards[j] {
+				p.pending[partitionID(t, fvn)] = movingPartition{t, fvn, p.Row.Shards[i], p.Shards[j]}
+			}
+		}
+	}
+	return nil
+}
+
+// Run copies and verifies every moving partition not moved yet, including the
+// dirty ones. An error in a partition does not stop the other partitions, the
+// partition is kept pending so a later Run retries it. Run starts the
+// Resharding if it was not started.
+func (p *Resharding) Run() error {
+	p.Start()
+	p.Row.m.RLock()
+	p.m.Lock()
+	if !p.scanned {
+		if err := p.scan(); err != nil {
+			p.m.Unlock()
+			p.Row.m.RUnlock()
+			return err
+		}
+		p.scanned = true
+	}
+	p.Row.m.RUnlock()
+	todo := []string{}
+	for id := range p.pending {
+		if !p.moved[id] {
+			todo = append(todo, id)
+		}
+	}
+	for id, mp := range p.dirty {
+		if _, ok := p.pending[id]; !ok || p.moved[id] {
+			p.pending[id] = mp
+			delete(p.moved, id)
+			todo = append(todo, id)
+		}
+	}
+	p.m.Unlock()
+	parallelism := p.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	mu := sync.Mutex{}
+	errs := []error(nil)
+	ch := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ch {
+				if err := p.move(id); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, id := range todo {
+		p.m.Lock()
+		for p.paused {
+			p.resumed.Wait()
+		}
+		p.m.Unlock()
+		ch <- id
+	}
+	close(ch)
+	wg.Wait()
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return h.MultipleErrors{Errors: errs}
+}
+
+// move copies a partition while its writes are blocked.
+func (p *Resharding) move(id string) error {
+	p.m.Lock()
+	mp := p.pending[id]
+	p.m.Unlock()
+	l := p.lock(id)
+	l.Lock()
+	defer l.Unlock()
+	if err := p.prune(mp); err != nil {
+		return mg.PartitionError{Partition: mp.fvn, Err: err}
+	}
+	m := mg.Migration{
+		Table:       mp.table,
+		Source:      mp.source,
+		Destination: mp.destination,
+		Partitions:  []r.FieldValuesByName{mp.fvn},
+		BatchSize:   p.BatchSize,
+		Verify:      true,
+	}
+	if _, err := m.Run(mg.Checkpoint{}); err != nil {
+		return err
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.moved[id] = true
+	delete(p.dirty, id)
+	if p.OnProgress != nil {
+		p.OnProgress(p.progress())
+	}
+	return nil
+}
+
+// prune deletes from the new shard the rows of a partition that are not in
+// the current shard.
+func (p *Resharding) prune(mp movingPartition) error {
+	src := r.RowRetrieve{Table: mp.table, FieldValues: mp.fvn, Consistent: true}
+	if err := mp.source.Access(&src); err != nil {
+		return h.RowProviderAccessError{Access: src, Err: err}
+	}
+	dst := r.RowRetrieve{Table: mp.table, FieldValues: mp.fvn, Consistent: true}
+	if err := mp.destination.Access(&dst); err != nil {
+		return h.RowProviderAccessError{Access: dst, Err: err}
+	}
+	keys := map[string]bool{}
+	for _, fvn := range src.RetrievedValues {
+		keys[string(mp.table.Encode(mp.table.PrimaryKey, fvn))] = true
+	}
+	for _, fvn := range dst.RetrievedValues {
+		if keys[string(mp.table.Encode(mp.table.PrimaryKey, fvn))] {
+			continue
+		}
+		kv := r.FieldValuesByName{}
+		for _, fn := range mp.table.PrimaryKey {
+			kv[fn] = fvn[fn]
+		}
+		rd := r.RowDelete{Table: mp.table, FieldValues: kv}
+		if err := mp.destination.Access(rd); err != nil {
+			return h.RowProviderAccessError{Access: rd, Err: err}
+		}
+	}
+	return nil
+}
+
+// access performs a RowAccess on its current shard and, if it is a write to a
+// moving partition, on its new shard too. Called with the lock of the
+// ShardedRow held for reading.
+func (p *Resharding) access(source *Row, a r.RowAccess) error {
+	if _, ok := a.(*r.RowRetrieve); ok {
+		return source.Access(a)
+	}
+	t, fvn, _ := accessFields(a)
+	j, err := route(p.Shards, p.Router, t, fvn)
+	if err != nil {
+		return err
+	}
+	destination := p.Shards[j]
+	if destination == source {
+		return source.Access(a)
+	}
+	id := partitionID(t, fvn)
+	l := p.lock(id)
+	l.RLock()
+	defer l.RUnlock()
+	if err := source.Access(a); err != nil {
+		return err
+	}
+	if err := destination.Access(a); err != nil {
+		kv := r.FieldValuesByName{}
+		for _, fn := range t.PartitionKey {
+			kv[fn] = fvn[fn]
+		}
+		p.m.Lock()
+		p.dirty[id] = movingPartition{t, kv, source, destination}
+		p.m.Unlock()
+	}
+	return nil
+}
+
+// accessSlice performs the RowAccesses of a current shard like access, with a
+// single AccessSlice on the current shard and another on each new shard, so the
+// Transaction and BatchSize of the Rows still apply. The locks of the moving
+// partitions are taken in order, so concurrent calls do not deadlock.
+func (p *Resharding) accessSlice(source *Row, aa []r.RowAccess) []error {
+	type write struct {
+		index int
+		id    string
+		table r.Table
+		fvn   r.FieldValuesByName
+	}
+	errs := make([]error, len(aa))
+	routed := make([]int, 0, len(aa))
+	moving := map[*Row][]write{}
+	locks := map[int]bool{}
+	for i, a := range aa {
+		if _, ok := a.(*r.RowRetrieve); ok {
+			routed = append(routed, i)
+			continue
+		}
+		t, fvn, _ := accessFields(a)
+		j, err := route(p.Shards, p.Router, t, fvn)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		routed = append(routed, i)
+		if destination := p.Shards[j]; destination != source {
+			id := partitionID(t, fvn)
+			locks[p.lockIndex(id)] = true
+			moving[destination] = append(moving[destination], write{i, id, t, fvn})
+		}
+	}
+	ordered := make([]int, 0, len(locks))
+	for l := range locks {
+		ordered = append(ordered, l)
+	}
+	sort.Ints(ordered)
+	for _, l := range ordered {
+		p.locks[l].RLock()
+		defer p.locks[l].RUnlock()
+	}
+	sa := make([]r.RowAccess, len(routed))
+	for j, i := range routed {
+		sa[j] = aa[i]
+	}
+	for j, err := range source.AccessSlice(sa) {
+		errs[routed[j]] = err
+	}
+	for destination, ws := range moving {
+		written := make([]write, 0, len(ws))
+		da := make([]r.RowAccess, 0, len(ws))
+		for _, w := range ws {
+			if errs[w.index] == nil {
+				written = append(written, w)
+				da = append(da, aa[w.index])
+			}
+		}
+		if len(da) == 0 {
+			continue
+		}
+		for j, err := range destination.AccessSlice(da) {
+			if err == nil {
+				continue
+			}
+			w := written[j]
+			kv := r.FieldValuesByName{}
+			for _, fn := range w.table.PartitionKey {
+				kv[fn] = w.fvn[fn]
+			}
+			p.m.Lock()
+			p.dirty[w.id] = movingPartition{w.table, kv, source, destination}
+			p.m.Unlock()
+		}
+	}
+	return errs
+}
+
+// Flip makes the ShardedRow use the new Shards and Router. It waits for the
+// RowAccesses in progress to finish and fails if some moving partition has not
+// been moved.
+func (p *Resharding) Flip() error {
+	p.Row.m.Lock()
+	defer p.Row.m.Unlock()
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.scanned || len(p.moved) < len(p.pending) || len(p.dirty) > 0 {
+		return IncompleteReshardingError{p.progress()}
+	}
+	p.Row.Shards = p.Shards
+	p.Row.Router = p.Router
+	p.Row.resharding = nil
+	return nil
+}
@@ -0,0 +1,229 @@
+package heptane
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+// TestRouter routes the encoded PartitionKeys found in Routes to the given
+// shard and the remaining ones to the shard 0.
+type TestRouter struct {
+	Routes map[string]int
+}
+
+func (p TestRouter) Route(key []byte, n int) int {
+	return p.Routes[string(key)]
+}
+
+func TestingResharding(t *testing.T) (*Resharding, sqlmock.Sqlmock, sqlmock.Sqlmock, func()) {
+	old, mocks, done := TestingShards(t, 2)
+	p := &Resharding{
+		Row:    &ShardedRow{Shards: old.Shards[:1]},
+		Shards: old.Shards,
+		Router: TestRouter{map[string]int{"s\x011": 1}},
+		Tables: []r.Table{TestingTable1()},
+	}
+	return p, mocks[0], mocks[1], done
+}
+
+func TestResharding_Flip_Incomplete(t *testing.T) {
+	p, _, _, done := TestingResharding(t)
+	defer done()
+	if err := p.Flip(); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Incomplete Resharding: 0 of 0 partitions moved, 0 dirty` {
+		t.Error(s)
+	}
+}
+
+func TestResharding_DualWrite(t *testing.T) {
+	p, mock0, mock1, done := TestingResharding(t)
+	defer done()
+	p.Start()
+	mock0.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock1.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnError(errors.New("problem"))
+	mock0.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("2", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	errs := p.Row.AccessSlice([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "2", "bar": "2"}},
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if pr := p.Progress(); pr.Dirty != 1 {
+		t.Error(pr)
+	}
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+	p.Abort()
+	if p.Row.resharding != nil {
+		t.Error(p.Row.resharding)
+	}
+}
+
+func TestResharding_DualWrite_Transaction(t *testing.T) {
+	p, mock0, mock1, done := TestingResharding(t)
+	defer done()
+	for _, s := range p.Shards {
+		s.Transaction = &sql.TxOptions{}
+	}
+	p.Start()
+	mock0.ExpectBegin()
+	for _, foo := range []string{"1", "2"} {
+		mock0.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+			WithArgs(foo, "2").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock0.ExpectCommit()
+	mock1.ExpectBegin()
+	mock1.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock1.ExpectCommit()
+	b := TestingTable1()
+	errs := p.Row.AccessSlice([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "2", "bar": "2"}},
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if pr := p.Progress(); pr.Dirty != 0 {
+		t.Error(pr)
+	}
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestResharding_Run_Flip(t *testing.T) {
+	p, mock0, mock1, done := TestingResharding(t)
+	defer done()
+	mock0.ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("1").AddRow("2"))
+	mock0.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}).AddRow("2", "3"))
+	mock1.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}).AddRow("2", "3").AddRow("9", "9"))
+	mock1.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock0.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}).AddRow("2", "3"))
+	mock1.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar', 'baz'\) VALUES \(\?, \?, \?\)`).
+		WithArgs("1", "2", "3").
+		WillReturnError(TestingPostgresError{"23505"})
+	mock1.ExpectExec(`UPDATE 'table1' SET 'baz' = \? WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("3", "1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock1.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}).AddRow("2", "3"))
+	progress := []ReshardingProgress{}
+	p.OnProgress = func(pr ReshardingProgress) { progress = append(progress, pr) }
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 1 || progress[0] != (ReshardingProgress{Moved: 1, Total: 1}) {
+		t.Error(progress)
+	}
+	if err := p.Flip(); err != nil {
+		t.Error(err)
+	}
+	mock1.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := p.Row.Access(r.RowDelete{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}); err != nil {
+		t.Error(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestResharding_Run_Error(t *testing.T) {
+	p, mock0, mock1, done := TestingResharding(t)
+	defer done()
+	mock0.ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("1"))
+	mock0.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnError(errors.New("problem"))
+	if err := p.Run(); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Partition map[foo:1] Error: heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{"foo":"1"}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:true} Error: Sql Error: problem` {
+		t.Error(s)
+	}
+	if err := p.Flip(); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Incomplete Resharding: 0 of 1 partitions moved, 0 dirty` {
+		t.Error(s)
+	}
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestResharding_Pause(t *testing.T) {
+	p, mock0, mock1, done := TestingResharding(t)
+	defer done()
+	mock0.ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("1"))
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		mock.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}))
+	}
+	mock0.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}))
+	mock1.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}))
+	p.Pause()
+	ch := make(chan error)
+	go func() { ch <- p.Run() }()
+	select {
+	case err := <-ch:
+		t.Fatal(err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if pr := p.Progress(); pr != (ReshardingProgress{Total: 1}) {
+		t.Error(pr)
+	}
+	p.Resume()
+	if err := <-ch; err != nil {
+		t.Error(err)
+	}
+	if err := p.Flip(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,62 @@
+package heptane
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Retryable reports whether the error of the database is transient, like a
+// deadlock, a lock timeout, a serialization failure or a lost connection, so
+// the RowAccess may succeed if it is performed again.
+func (e SqlError) Retryable() bool {
+	switch semantic(e.Err) {
+	case ErrTimeout, ErrConnectionLost:
+		return true
+	}
+	if state, ok := sqlState(e.Err); ok {
+		// Transaction rollback, including deadlocks and serialization
+		// failures.
+		return strings.HasPrefix(state, "40")
+	}
+	if n, ok := number(e.Err); ok {
+		// Deadlock victim in SQL Server, lock wait timeout and deadlock
+		// in MySQL.
+		return n == 1205 || n == 1213
+	}
+	return false
+}
+
+// Retryable reports true: a RowAccess of a rolled back transaction may be
+// performed again as long as the RowAccess that rolled it back is retryable.
+func (e RolledBackError) Retryable() bool {
+	return true
+}
+
+// sqlState returns the SQLSTATE code of the errors of the Postgres drivers.
+func sqlState(err error) (string, bool) {
+	if se := interface{ SQLState() string }(nil); errors.As(err, &se) {
+		return se.SQLState(), true
+	}
+	return "", false
+}
+
+// number returns the error number of the errors of the MySQL and SQL Server
+// drivers, which expose it only as a field, unwrapping the error until one
+// has it.
+func number(err error) (int64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		f := v.FieldByName("Number")
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return f.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(f.Uint()), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,42 @@
+package heptane
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type TestingPostgresError struct {
+	Code string
+}
+
+func (e TestingPostgresError) Error() string    { return "postgres " + e.Code }
+func (e TestingPostgresError) SQLState() string { return e.Code }
+
+type TestingMySQLError struct {
+	Number uint16
+}
+
+func (e *TestingMySQLError) Error() string { return fmt.Sprint("mysql ", e.Number) }
+
+func TestSqlError_Retryable(t *testing.T) {
+	for _, c := range []struct {
+		err       error
+		retryable bool
+	}{
+		{driver.ErrBadConn, true},
+		{errors.New("problem"), false},
+		{TestingPostgresError{"40P01"}, true},
+		{TestingPostgresError{"40001"}, true},
+		{TestingPostgresError{"08006"}, true},
+		{TestingPostgresError{"23505"}, false},
+		{&TestingMySQLError{1213}, true},
+		{&TestingMySQLError{1062}, false},
+		{fmt.Errorf("wrapped: %w", &TestingMySQLError{1213}), true},
+	} {
+		if b := (SqlError{c.err}).Retryable(); b != c.retryable {
+			t.Error(c.err, b)
+		}
+	}
+}
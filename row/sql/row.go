@@ -3,6 +3,7 @@ package heptane
 import (
 	"database/sql"
 	"strings"
+	"time"
 
 	r "github.com/heptanes/heptane/row"
 )
@@ -21,30 +22,108 @@ type Dialect interface {
 	WritePlaceholder(sb *strings.Builder, i int)
 }
 
-// Row implements RowProvider. Each RowAccess is performed on a single sql.DB.
+// Row implements RowProvider. Each RowAccess is performed on a single sql.DB:
+// writes on the primary DB and RowRetrieves on one of the Replicas, if any,
+// unless they are Consistent.
 type Row struct {
-	DB      *sql.DB
+	// DB is the primary database.
+	DB *sql.DB
+	// Dialect generates the sql strings.
 	Dialect Dialect
+	// Replicas contains the read replicas of the primary database.
+	Replicas []*sql.DB
+	// Balancer selects the replica of each RowRetrieve. A nil Balancer
+	// means RoundRobin.
+	Balancer Balancer
+	// Statements is the maximum number of sql strings cached, each with its
+	// prepared statements. Zero disables the cache and the prepared
+	// statements.
+	Statements int
+	// BatchSize is the maximum number of RowAccesses coalesced by AccessSlice
+	// in a single statement. Values lower than 2 disable the coalescing.
+	BatchSize int
+	// Transaction, if not nil, makes AccessSlice behave like AccessSliceTx
+	// with the given options. Within a ShardedRow, the RowAccesses of each
+	// shard are performed in a different transaction.
+	Transaction *sql.TxOptions
+	// Upsert makes every RowCreate and RowUpdate overwrite the existing row
+	// with the same PrimaryKey, if any, or create it otherwise, like
+	// Cassandra does. The Dialect must implement UpsertDialect.
+	Upsert bool
+	// VerifyTables makes VerifyTable query the schema of the database, so
+	// the Tables are verified when they are registered. False means every
+	// Table is accepted without any query.
+	VerifyTables bool
+
+	roundRobin RoundRobin
+	statements statementCache
 }
 
-func (p *Row) exec(query string, args ...interface{}) (err error) {
-	if _, err = p.DB.Exec(query, args...); err != nil {
+// exec executes a statement on the primary database, within the given
+// transaction if it is not nil.
+func (p *Row) exec(tx *sql.Tx, tn r.TableName, sig string, build func() string, args ...interface{}) (err error) {
+	query, stmt, release := p.statement(p.DB, tn, sig, build)
+	defer release()
+	switch {
+	case tx != nil && stmt != nil:
+		_, err = tx.Stmt(stmt).Exec(args...)
+	case tx != nil:
+		_, err = tx.Exec(query, args...)
+	case stmt != nil:
+		_, err = stmt.Exec(args...)
+	default:
+		_, err = p.DB.Exec(query, args...)
+	}
+	if err != nil {
 		err = SqlError{err}
 		return
 	}
 	return
 }
 
-func (p *Row) query(b r.Table, query string, args ...interface{}) (fnvs []r.FieldValuesByName, err error) {
-	rows, err := p.DB.Query(query, args...)
+// reader returns the database of a query and a function to be called with the
+// error of the query when it is done. Queries within a transaction are performed on the primary
+// database.
+func (p *Row) reader(tx *sql.Tx, consistent bool) (*sql.DB, func(error)) {
+	if tx != nil || consistent || len(p.Replicas) == 0 {
+		return p.DB, func(error) {}
+	}
+	b := p.Balancer
+	if b == nil {
+		b = &p.roundRobin
+	}
+	i := b.Select(len(p.Replicas))
+	start := time.Now()
+	return p.Replicas[i], func(err error) {
+		b.Observe(i, time.Since(start), err)
+	}
+}
+
+// query executes a query on the given database, within the given transaction
+// if it is not nil, and returns the values of the given fields of the selected
+// rows together with the values in kv.
+func (p *Row) query(db *sql.DB, tx *sql.Tx, b r.Table, fns []r.FieldName, kv r.FieldValuesByName, sig string, build func() string, args ...interface{}) (fnvs []r.FieldValuesByName, err error) {
+	query, stmt, release := p.statement(db, b.Name, sig, build)
+	defer release()
+	var rows *sql.Rows
+	switch {
+	case tx != nil && stmt != nil:
+		rows, err = tx.Stmt(stmt).Query(args...)
+	case tx != nil:
+		rows, err = tx.Query(query, args...)
+	case stmt != nil:
+		rows, err = stmt.Query(args...)
+	default:
+		rows, err = db.Query(query, args...)
+	}
 	if err != nil {
 		err = SqlError{err}
 		return
 	}
 	defer rows.Close()
 	for rows.Next() {
-		scan := make([]interface{}, len(b.Values))
-		for i, fn := range b.Values {
+		scan := make([]interface{}, len(fns))
+		for i, fn := range fns {
 			ft := b.Types[fn]
 			switch ft {
 			case "bool":
@@ -59,9 +138,12 @@ func (p *Row) query(b r.Table, query string, args ...interface{}) (fnvs []r.Fiel
 			err = SqlError{err}
 			return
 		}
-		fvn := r.FieldValuesByName{}
+		fvn := make(r.FieldValuesByName, len(kv)+len(fns))
+		for fn, fv := range kv {
+			fvn[fn] = fv
+		}
 		for i, v := range scan {
-			fn := b.Values[i]
+			fn := fns[i]
 			ft := b.Types[fn]
 			switch ft {
 			case "bool":
@@ -81,10 +163,65 @@ func (p *Row) query(b r.Table, query string, args ...interface{}) (fnvs []r.Fiel
 	return
 }
 
+// signature identifies the sql string of a RowAccess: its kind, its table, the
+// names and types of the fields of the table and, for every field, whether it
+// is absent, null or present. Two versions of a Table with the same Name have
+// different signatures.
+func signature(kind byte, b r.Table, fvn r.FieldValuesByName) string {
+	sb := &strings.Builder{}
+	sb.WriteByte(kind)
+	sb.WriteString(string(b.Name))
+	sb.WriteByte(0)
+	for _, fn := range b.PartitionKey {
+		sb.WriteString(string(fn))
+		sb.WriteByte(0)
+	}
+	for _, fns := range [][]r.FieldName{b.PrimaryKey, b.Values} {
+		sb.WriteByte(0)
+		for _, fn := range fns {
+			sb.WriteString(string(fn))
+			sb.WriteByte(0)
+			sb.WriteString(string(b.Types[fn]))
+			sb.WriteByte(0)
+			switch fv, ok := fvn[fn]; {
+			case !ok:
+				sb.WriteByte('-')
+			case fv == nil:
+				sb.WriteByte('n')
+			default:
+				sb.WriteByte('v')
+			}
+		}
+	}
+	return sb.String()
+}
+
 func (p *Row) Create(a r.RowCreate) error {
+	return p.create(nil, a)
+}
+
+func (p *Row) create(tx *sql.Tx, a r.RowCreate) error {
 	if err := a.Table.Validate(); err != nil {
 		return err
 	}
+	if p.Upsert {
+		return p.upsert(tx, a.Table, a.FieldValues)
+	}
+	args := make([]interface{}, 0, len(a.Table.PrimaryKey)+len(a.Table.Values))
+	for _, fn := range a.Table.PrimaryKey {
+		args = append(args, a.FieldValues[fn])
+	}
+	for _, fn := range a.Table.Values {
+		if fv, ok := a.FieldValues[fn]; ok {
+			args = append(args, fv)
+		}
+	}
+	return p.exec(tx, a.Table.Name, signature('c', a.Table, a.FieldValues), func() string {
+		return p.createString(a)
+	}, args...)
+}
+
+func (p *Row) createString(a r.RowCreate) string {
 	sb := &strings.Builder{}
 	sb.WriteString("INSERT INTO ")
 	p.Dialect.WriteTableName(sb, a.Table.Name)
@@ -96,41 +233,66 @@ func (p *Row) Create(a r.RowCreate) error {
 		p.Dialect.WriteFieldName(sb, fn)
 	}
 	for _, fn := range a.Table.Values {
-		_, ok := a.FieldValues[fn]
-		if ok {
+		if _, ok := a.FieldValues[fn]; ok {
 			sb.WriteString(", ")
 			p.Dialect.WriteFieldName(sb, fn)
 		}
 	}
 	sb.WriteString(") VALUES (")
-	args := make([]interface{}, 0, len(a.Table.PrimaryKey)+len(a.Table.Values))
-	for i, fn := range a.Table.PrimaryKey {
+	n := 0
+	for i := range a.Table.PrimaryKey {
 		if i != 0 {
 			sb.WriteString(", ")
 		}
-		p.Dialect.WritePlaceholder(sb, i)
-		fv := a.FieldValues[fn]
-		args = append(args, fv)
+		p.Dialect.WritePlaceholder(sb, n)
+		n++
 	}
 	for _, fn := range a.Table.Values {
-		fv, ok := a.FieldValues[fn]
-		if ok {
+		if _, ok := a.FieldValues[fn]; ok {
 			sb.WriteString(", ")
-			p.Dialect.WritePlaceholder(sb, len(args))
-			args = append(args, fv)
+			p.Dialect.WritePlaceholder(sb, n)
+			n++
 		}
 	}
 	sb.WriteString(")")
-	return p.exec(sb.String(), args...)
+	return sb.String()
 }
 
 func (p *Row) Retrieve(a *r.RowRetrieve) error {
+	return p.retrieve(nil, a)
+}
+
+func (p *Row) retrieve(tx *sql.Tx, a *r.RowRetrieve) error {
 	if err := a.Table.Validate(); err != nil {
 		return err
 	}
+	kv := r.FieldValuesByName{}
+	fns := make([]r.FieldName, 0, len(a.Table.PrimaryKey)+len(a.Table.Values))
+	args := make([]interface{}, 0, len(a.Table.PrimaryKey))
+	for _, fn := range a.Table.PrimaryKey {
+		if fv, ok := a.FieldValues[fn]; ok {
+			kv[fn] = fv
+			if fv != nil {
+				args = append(args, fv)
+			}
+		} else {
+			fns = append(fns, fn)
+		}
+	}
+	fns = append(fns, a.Table.Values...)
+	db, done := p.reader(tx, a.Consistent)
+	fvn, err := p.query(db, tx, a.Table, fns, kv, signature('r', a.Table, a.FieldValues), func() string {
+		return p.retrieveString(a, fns)
+	}, args...)
+	done(err)
+	a.RetrievedValues = fvn
+	return err
+}
+
+func (p *Row) retrieveString(a *r.RowRetrieve, fns []r.FieldName) string {
 	sb := &strings.Builder{}
 	sb.WriteString("SELECT ")
-	for i, fn := range a.Table.Values {
+	for i, fn := range fns {
 		if i != 0 {
 			sb.WriteString(", ")
 		}
@@ -139,116 +301,189 @@ func (p *Row) Retrieve(a *r.RowRetrieve) error {
 	sb.WriteString(" FROM ")
 	p.Dialect.WriteTableName(sb, a.Table.Name)
 	sb.WriteString(" WHERE ")
-	args := make([]interface{}, 0, len(a.Table.PrimaryKey))
-	for i, fn := range a.Table.PrimaryKey {
-		if i != 0 {
+	p.writeWhere(sb, a.Table.PrimaryKey, a.FieldValues, 0)
+	return sb.String()
+}
+
+// writeWhere writes the conditions of a WHERE clause comparing the given
+// fields, if present, to their values. The placeholders are numbered from n.
+func (p *Row) writeWhere(sb *strings.Builder, fns []r.FieldName, fvn r.FieldValuesByName, n int) {
+	first := true
+	for _, fn := range fns {
+		fv, ok := fvn[fn]
+		if !ok {
+			continue
+		}
+		if !first {
 			sb.WriteString(" AND ")
 		}
+		first = false
 		p.Dialect.WriteFieldName(sb, fn)
-		fv := a.FieldValues[fn]
 		if fv == nil {
 			sb.WriteString(" IS NULL")
 			continue
 		}
 		sb.WriteString(" = ")
-		p.Dialect.WritePlaceholder(sb, i)
-		args = append(args, fv)
+		p.Dialect.WritePlaceholder(sb, n)
+		n++
 	}
-	fvn, err := p.query(a.Table, sb.String(), args...)
-	a.RetrievedValues = fvn
-	return err
+}
+
+// keyArgs appends to args the non null values of the PrimaryKey.
+func keyArgs(args []interface{}, b r.Table, fvn r.FieldValuesByName) []interface{} {
+	for _, fn := range b.PrimaryKey {
+		if fv := fvn[fn]; fv != nil {
+			args = append(args, fv)
+		}
+	}
+	return args
+}
+
+// primaryKey returns the values of the PrimaryKey, with nil for the absent
+// ones.
+func primaryKey(b r.Table, fvn r.FieldValuesByName) r.FieldValuesByName {
+	kv := make(r.FieldValuesByName, len(b.PrimaryKey))
+	for _, fn := range b.PrimaryKey {
+		kv[fn] = fvn[fn]
+	}
+	return kv
 }
 
 func (p *Row) Update(a r.RowUpdate) error {
+	return p.update(nil, a)
+}
+
+func (p *Row) update(tx *sql.Tx, a r.RowUpdate) error {
 	if err := a.Table.Validate(); err != nil {
 		return err
 	}
+	if p.Upsert {
+		return p.upsert(tx, a.Table, a.FieldValues)
+	}
+	args := make([]interface{}, 0, len(a.Table.PrimaryKey)+len(a.Table.Values))
+	for _, fn := range a.Table.Values {
+		if fv, ok := a.FieldValues[fn]; ok {
+			args = append(args, fv)
+		}
+	}
+	args = keyArgs(args, a.Table, a.FieldValues)
+	return p.exec(tx, a.Table.Name, signature('u', a.Table, a.FieldValues), func() string {
+		return p.updateString(a)
+	}, args...)
+}
+
+func (p *Row) updateString(a r.RowUpdate) string {
 	sb := &strings.Builder{}
 	sb.WriteString("UPDATE ")
 	p.Dialect.WriteTableName(sb, a.Table.Name)
 	sb.WriteString(" SET ")
-	args := make([]interface{}, 0, len(a.Table.PrimaryKey)+len(a.Table.Values))
-	for i, fn := range a.Table.Values {
-		fv, ok := a.FieldValues[fn]
-		if ok {
-			if i != 0 {
+	n := 0
+	for _, fn := range a.Table.Values {
+		if _, ok := a.FieldValues[fn]; ok {
+			if n != 0 {
 				sb.WriteString(", ")
 			}
 			p.Dialect.WriteFieldName(sb, fn)
 			sb.WriteString(" = ")
-			p.Dialect.WritePlaceholder(sb, len(args))
-			args = append(args, fv)
+			p.Dialect.WritePlaceholder(sb, n)
+			n++
 		}
 	}
 	sb.WriteString(" WHERE ")
-	for i, fn := range a.Table.PrimaryKey {
-		if i != 0 {
-			sb.WriteString(" AND ")
-		}
-		p.Dialect.WriteFieldName(sb, fn)
-		fv := a.FieldValues[fn]
-		if fv == nil {
-			sb.WriteString(" IS NULL")
-			continue
+	p.writeWhere(sb, a.Table.PrimaryKey, primaryKey(a.Table, a.FieldValues), n)
+	return sb.String()
+}
+
+func (p *Row) Delete(a r.RowDelete) error {
+	return p.delete(nil, a)
+}
+
+// upsert writes the present fields of a row, overwriting the existing row if
+// any.
+func (p *Row) upsert(tx *sql.Tx, b r.Table, fvn r.FieldValuesByName) error {
+	d, ok := p.Dialect.(UpsertDialect)
+	if !ok {
+		return UnsupportedUpsertError{p.Dialect}
+	}
+	values := make([]r.FieldName, 0, len(b.Values))
+	args := make([]interface{}, 0, len(b.PrimaryKey)+len(b.Values))
+	for _, fn := range b.PrimaryKey {
+		args = append(args, fvn[fn])
+	}
+	for _, fn := range b.Values {
+		if fv, ok := fvn[fn]; ok {
+			values = append(values, fn)
+			args = append(args, fv)
 		}
-		sb.WriteString(" = ")
-		p.Dialect.WritePlaceholder(sb, i)
-		args = append(args, fv)
 	}
-	return p.exec(sb.String(), args...)
+	return p.exec(tx, b.Name, signature('C', b, fvn), func() string {
+		sb := &strings.Builder{}
+		d.WriteUpsert(sb, b.Name, b.PrimaryKey, values)
+		return sb.String()
+	}, args...)
 }
 
-func (p *Row) Delete(a r.RowDelete) error {
+func (p *Row) delete(tx *sql.Tx, a r.RowDelete) error {
 	if err := a.Table.Validate(); err != nil {
 		return err
 	}
+	args := keyArgs(make([]interface{}, 0, len(a.Table.PrimaryKey)), a.Table, a.FieldValues)
+	kv := primaryKey(a.Table, a.FieldValues)
+	return p.exec(tx, a.Table.Name, signature('d', a.Table, kv), func() string {
+		return p.deleteString(a.Table, kv)
+	}, args...)
+}
+
+func (p *Row) deleteString(b r.Table, kv r.FieldValuesByName) string {
 	sb := &strings.Builder{}
 	sb.WriteString("DELETE FROM ")
-	p.Dialect.WriteTableName(sb, a.Table.Name)
+	p.Dialect.WriteTableName(sb, b.Name)
 	sb.WriteString(" WHERE ")
-	args := make([]interface{}, 0, len(a.Table.PrimaryKey))
-	for i, fn := range a.Table.PrimaryKey {
-		if i != 0 {
-			sb.WriteString(" AND ")
-		}
-		p.Dialect.WriteFieldName(sb, fn)
-		fv := a.FieldValues[fn]
-		if fv == nil {
-			sb.WriteString(" IS NULL")
-			continue
-		}
-		sb.WriteString(" = ")
-		p.Dialect.WritePlaceholder(sb, i)
-		args = append(args, fv)
-	}
-	return p.exec(sb.String(), args...)
+	p.writeWhere(sb, b.PrimaryKey, kv, 0)
+	return sb.String()
 }
 
 // Access implements RowProvider.
 func (p *Row) Access(a r.RowAccess) error {
+	return p.access(nil, a)
+}
+
+func (p *Row) access(tx *sql.Tx, a r.RowAccess) error {
 	switch a := a.(type) {
 	case r.RowCreate:
-		return p.Create(a)
+		return p.create(tx, a)
 	case *r.RowCreate:
-		return p.Create(*a)
+		return p.create(tx, *a)
 	case *r.RowRetrieve:
-		return p.Retrieve(a)
+		return p.retrieve(tx, a)
 	case r.RowUpdate:
-		return p.Update(a)
+		return p.update(tx, a)
 	case *r.RowUpdate:
-		return p.Update(*a)
+		return p.update(tx, *a)
 	case r.RowDelete:
-		return p.Delete(a)
+		return p.delete(tx, a)
 	case *r.RowDelete:
-		return p.Delete(*a)
+		return p.delete(tx, *a)
 	}
 	return UnsupportedRowAccessTypeError{a}
 }
 
-// AccessSlice implements RowProvider.
-func (p *Row) AccessSlice(aa []r.RowAccess) (errs []error) {
-	for _, a := range aa {
-		errs = append(errs, p.Access(a))
+// ScanPartitions implements PartitionScanner.
+func (p *Row) ScanPartitions(b r.Table) ([]r.FieldValuesByName, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
 	}
-	return
+	return p.query(p.DB, nil, b, b.PartitionKey, nil, signature('s', b, nil), func() string {
+		sb := &strings.Builder{}
+		sb.WriteString("SELECT DISTINCT ")
+		for i, fn := range b.PartitionKey {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			p.Dialect.WriteFieldName(sb, fn)
+		}
+		sb.WriteString(" FROM ")
+		p.Dialect.WriteTableName(sb, b.Name)
+		return sb.String()
+	})
 }
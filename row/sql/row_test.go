@@ -1,6 +1,7 @@
 package heptane
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -42,7 +43,7 @@ func (d TestDialect) WritePlaceholder(sb *strings.Builder, i int) {
 func TestCreate_ValidationError(t *testing.T) {
 	b := TestingTable1()
 	b.Name = ""
-	rp := Row{nil, TestDialect{}}
+	rp := Row{Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -62,7 +63,7 @@ func TestCreate_MissingValue(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -85,7 +86,7 @@ func TestCreate_MissingValue_AmongMultipleValues(t *testing.T) {
 	b := TestingTable1()
 	b.Values = []r.FieldName{"baz", "qux"}
 	b.Types = r.FieldTypesByName{"foo": "string", "bar": "string", "baz": "string", "qux": "string"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "qux": "4"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -106,7 +107,7 @@ func TestCreate_Null(t *testing.T) {
 		WithArgs("1", "2", nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": nil}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -127,7 +128,7 @@ func TestCreate_Bool(t *testing.T) {
 		WithArgs(false, true, false).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true, "baz": false}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -148,7 +149,7 @@ func TestCreate_ByRef(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -160,11 +161,11 @@ func TestCreate_ByRef(t *testing.T) {
 
 func TestRetrieve_UnsupportedRowAccessTypeError(t *testing.T) {
 	b := TestingTable1()
-	rp := Row{nil, TestDialect{}}
+	rp := Row{Dialect: TestDialect{}}
 	a := r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
-	} else if s := err.Error(); s != `Unsupported RowAccess Type: heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{}, RetrievedValues:[]heptane.FieldValuesByName(nil)}` {
+	} else if s := err.Error(); s != `Unsupported RowAccess Type: heptane.RowRetrieve{Table:heptane.Table{Name:"table1", PartitionKey:[]heptane.FieldName{"foo"}, PrimaryKey:[]heptane.FieldName{"foo", "bar"}, Values:[]heptane.FieldName{"baz"}, Types:heptane.FieldTypesByName{"bar":"string", "baz":"string", "foo":"string"}, PrimaryKeyCachePrefix:[]string{"table1_pk", "0"}}, FieldValues:heptane.FieldValuesByName{}, RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false}` {
 		t.Error(s)
 	}
 }
@@ -172,7 +173,7 @@ func TestRetrieve_UnsupportedRowAccessTypeError(t *testing.T) {
 func TestRetrieve_ValidationError(t *testing.T) {
 	b := TestingTable1()
 	b.Name = ""
-	rp := Row{nil, TestDialect{}}
+	rp := Row{Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -192,12 +193,12 @@ func TestRetrieve_SingleSelect(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
 	}
-	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":"3"}}` {
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1"}}` {
 		t.Error(s)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -218,12 +219,12 @@ func TestRetrieve_MultipleSelect(t *testing.T) {
 	b := TestingTable1()
 	b.Values = []r.FieldName{"baz", "qux"}
 	b.Types = r.FieldTypesByName{"foo": "string", "bar": "string", "baz": "string", "qux": "string"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
 	}
-	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":"3", "qux":"4"}}` {
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1", "qux":"4"}}` {
 		t.Error(s)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -243,12 +244,12 @@ func TestRetrieve_SinglePrimaryKey(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
 	b := TestingTable1()
 	b.PrimaryKey = []r.FieldName{"foo"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
 	}
-	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":"3"}}` {
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":"3", "foo":"1"}}` {
 		t.Error(s)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -267,12 +268,12 @@ func TestRetrieve_NullInWhere(t *testing.T) {
 		WithArgs().
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": nil, "bar": nil}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
 	}
-	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":"3"}}` {
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":heptane.FieldValue(nil), "baz":"3", "foo":heptane.FieldValue(nil)}}` {
 		t.Error(s)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -292,12 +293,36 @@ func TestRetrieve_Bool(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("false"))
 	b := TestingTable1()
 	b.Types = r.FieldTypesByName{"foo": "bool", "bar": "bool", "baz": "bool"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
 	}
-	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"baz":false}}` {
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":true, "baz":false, "foo":false}}` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetrieve_PartitionKey(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT 'bar', 'baz' FROM 'table1' WHERE 'foo' = \?`).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"bar", "baz"}).AddRow("2", "3").AddRow("4", "5"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1"}, heptane.FieldValuesByName{"bar":"4", "baz":"5", "foo":"1"}}` {
 		t.Error(s)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -308,7 +333,7 @@ func TestRetrieve_Bool(t *testing.T) {
 func TestUpdate_ValidationError(t *testing.T) {
 	b := TestingTable1()
 	b.Name = ""
-	rp := Row{nil, TestDialect{}}
+	rp := Row{Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -328,7 +353,7 @@ func TestUpdate_SingleSet(t *testing.T) {
 		WithArgs("3", "1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -351,7 +376,7 @@ func TestUpdate_MultipleSet(t *testing.T) {
 	b := TestingTable1()
 	b.Values = []r.FieldName{"baz", "qux"}
 	b.Types = r.FieldTypesByName{"foo": "string", "bar": "string", "baz": "string", "qux": "string"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3", "qux": "4"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -373,7 +398,7 @@ func TestUpdate_SinglePrimaryKey(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
 	b.PrimaryKey = []r.FieldName{"foo"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "baz": "3"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -394,7 +419,7 @@ func TestUpdate_NullInSet(t *testing.T) {
 		WithArgs(nil, "1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": nil}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -415,7 +440,7 @@ func TestUpdate_NullInWhere(t *testing.T) {
 		WithArgs("3").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": nil, "bar": nil, "baz": "3"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -436,7 +461,7 @@ func TestUpdate_Bool(t *testing.T) {
 		WithArgs(false, false, true).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true, "baz": false}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -457,7 +482,7 @@ func TestUpdate_ByRef(t *testing.T) {
 		WithArgs(false, false, true).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true, "baz": false}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -467,10 +492,39 @@ func TestUpdate_ByRef(t *testing.T) {
 	}
 }
 
+func TestUpdate_Missing(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectExec(`UPDATE 'table1' SET 'baz' = \? WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("3", "1", "2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	// Like in row/memory, a missing row is not an error.
+	for _, a := range []r.RowAccess{
+		r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}},
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+	} {
+		if err := rp.Access(a); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestDelete_ValidationError(t *testing.T) {
 	b := TestingTable1()
 	b.Name = ""
-	rp := Row{nil, TestDialect{}}
+	rp := Row{Dialect: TestDialect{}}
 	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -490,7 +544,7 @@ func TestDelete_MultiplePrimaryKey(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -512,7 +566,7 @@ func TestDelete_SinglePrimaryKey(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
 	b.PrimaryKey = []r.FieldName{"foo"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -533,7 +587,7 @@ func TestDelete_NullInWhere(t *testing.T) {
 		WithArgs().
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": nil, "bar": nil}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -554,7 +608,7 @@ func TestDelete_Bool(t *testing.T) {
 		WithArgs(false, true).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -575,7 +629,7 @@ func TestDelete_ByRef(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err != nil {
 		t.Error(err)
@@ -596,7 +650,7 @@ func TestCreate_QueryError(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnError(errors.New("problem"))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -619,7 +673,7 @@ func TestRetrieve_QueryError(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnError(errors.New("problem"))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -643,7 +697,7 @@ func TestRetrieve_ScanError(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("invalid bool"))
 	b := TestingTable1()
 	b.Types = r.FieldTypesByName{"foo": "bool", "bar": "bool", "baz": "bool"}
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": false, "bar": true}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -666,7 +720,7 @@ func TestRetrieve_RowError(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3").RowError(0, errors.New("problem")))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	if err := rp.Access(a); err == nil {
 		t.Error(err)
@@ -689,7 +743,7 @@ func TestCreate_BySlice(t *testing.T) {
 		WithArgs("1", "2").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	b := TestingTable1()
-	rp := Row{db, TestDialect{}}
+	rp := Row{DB: db, Dialect: TestDialect{}}
 	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
 	errs := rp.AccessSlice([]r.RowAccess{a})
 	if errs == nil {
@@ -705,3 +759,189 @@ func TestCreate_BySlice(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestScanPartitions_ValidationError(t *testing.T) {
+	b := TestingTable1()
+	b.Name = ""
+	rp := Row{Dialect: TestDialect{}}
+	if _, err := rp.ScanPartitions(b); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Empty TableName in Table` {
+		t.Error(s)
+	}
+}
+
+func TestScanPartitions_OK(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("1").AddRow("2"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	if fvns, err := rp.ScanPartitions(b); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%#v", fvns); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"foo":"1"}, heptane.FieldValuesByName{"foo":"2"}}` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScanPartitions_QueryError(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnError(errors.New("problem"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	if _, err := rp.ScanPartitions(b); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Sql Error: problem` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetrieve_Replicas(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	replica1, mock1, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica1.Close()
+	replica2, mock2, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica2.Close()
+	for _, m := range []sqlmock.Sqlmock{mock1, mock2, mock1} {
+		m.ExpectQuery(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+			WithArgs("1", "2").
+			WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
+	}
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Replicas: []*sql.DB{replica1, replica2}}
+	for i := 0; i < 3; i++ {
+		a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+		if err := rp.Access(a); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, m := range []sqlmock.Sqlmock{mock, mock1, mock2} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRetrieve_Replicas_Failed(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	replica1, mock1, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica1.Close()
+	replica2, mock2, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica2.Close()
+	mock1.ExpectQuery(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnError(errors.New("problem"))
+	for i := 0; i < 2; i++ {
+		mock2.ExpectQuery(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+			WithArgs("1", "2").
+			WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
+	}
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Replicas: []*sql.DB{replica1, replica2}, Balancer: &LeastLatency{}}
+	for i := 0; i < 3; i++ {
+		a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+		if err := rp.Access(a); (err != nil) != (i == 0) {
+			t.Error(i, err)
+		}
+	}
+	for _, m := range []sqlmock.Sqlmock{mock, mock1, mock2} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRetrieve_Replicas_Consistent(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	replica, mock1, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica.Close()
+	mock.ExpectQuery(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Replicas: []*sql.DB{replica}, Balancer: &LeastLatency{}}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}, Consistent: true}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	for _, m := range []sqlmock.Sqlmock{mock, mock1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCreate_Replicas(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	replica, mock1, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica.Close()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Replicas: []*sql.DB{replica}}
+	a := r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	for _, m := range []sqlmock.Sqlmock{mock, mock1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
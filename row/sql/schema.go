@@ -0,0 +1,178 @@
+package heptane
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	h "github.com/heptanes/heptane"
+	r "github.com/heptanes/heptane/row"
+)
+
+// SchemaDialect is the interface of the Dialects able to query the schema of
+// the database.
+type SchemaDialect interface {
+	Dialect
+
+	// WritePrimaryKeyQuery writes to the Builder a query selecting the name
+	// of every field of the primary key of a table of the current schema, in
+	// order. The name of the table is its only argument.
+	WritePrimaryKeyQuery(sb *strings.Builder)
+}
+
+// writeInformationSchemaPrimaryKeyQuery writes a primary key query for the
+// databases providing the standard INFORMATION_SCHEMA views. The schema is the
+// sql expression of the current schema.
+func writeInformationSchemaPrimaryKeyQuery(sb *strings.Builder, d Dialect, schema string) {
+	sb.WriteString("SELECT kcu.column_name FROM information_schema.table_constraints tc")
+	sb.WriteString(" JOIN information_schema.key_column_usage kcu")
+	sb.WriteString(" ON tc.constraint_name = kcu.constraint_name")
+	sb.WriteString(" AND tc.table_schema = kcu.table_schema")
+	sb.WriteString(" AND tc.table_name = kcu.table_name")
+	sb.WriteString(" WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = ")
+	sb.WriteString(schema)
+	sb.WriteString(" AND tc.table_name = ")
+	d.WritePlaceholder(sb, 0)
+	sb.WriteString(" ORDER BY kcu.ordinal_position")
+}
+
+// WritePrimaryKeyQuery implements SchemaDialect.
+func (d Postgres) WritePrimaryKeyQuery(sb *strings.Builder) {
+	writeInformationSchemaPrimaryKeyQuery(sb, d, "current_schema()")
+}
+
+// WritePrimaryKeyQuery implements SchemaDialect.
+func (d MySQL) WritePrimaryKeyQuery(sb *strings.Builder) {
+	writeInformationSchemaPrimaryKeyQuery(sb, d, "DATABASE()")
+}
+
+// WritePrimaryKeyQuery implements SchemaDialect.
+func (d SQLServer) WritePrimaryKeyQuery(sb *strings.Builder) {
+	writeInformationSchemaPrimaryKeyQuery(sb, d, "SCHEMA_NAME()")
+}
+
+// compatibleTypes contains the database types of the columns that may store
+// the values of each FieldType.
+var compatibleTypes = map[r.FieldType][]string{
+	"bool": {"BOOL", "BOOLEAN", "BIT", "TINYINT"},
+	"string": {"CHAR", "VARCHAR", "TEXT", "NCHAR", "NVARCHAR", "NTEXT", "BPCHAR",
+		"CHARACTER", "CHARACTER VARYING", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT",
+		"CLOB", "UUID", "UNIQUEIDENTIFIER"},
+}
+
+func compatibleType(ft r.FieldType, dt string) bool {
+	if dt == "" {
+		// The driver does not report the type.
+		return true
+	}
+	for _, t := range compatibleTypes[ft] {
+		if strings.EqualFold(t, dt) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyTable implements TableVerifier. If VerifyTables is true, it verifies
+// that every field of the Table is a column of a compatible type and, if the
+// Dialect implements SchemaDialect, that the PrimaryKey matches the primary key
+// of the table. Otherwise it does nothing.
+func (p *Row) VerifyTable(b r.Table) error {
+	if !p.VerifyTables {
+		return nil
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	cts, err := p.columnTypes(b.Name)
+	if err != nil {
+		return err
+	}
+	types := map[string]string{}
+	for _, ct := range cts {
+		types[ct.Name()] = ct.DatabaseTypeName()
+	}
+	diffs := []string(nil)
+	for _, fns := range [][]r.FieldName{b.PrimaryKey, b.Values} {
+		for _, fn := range fns {
+			dt, ok := types[string(fn)]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("Missing Column for Field %v", fn))
+			} else if ft := b.Types[fn]; !compatibleType(ft, dt) {
+				diffs = append(diffs, fmt.Sprintf("Column %v of Type %v incompatible with FieldType %v", fn, dt, ft))
+			}
+		}
+	}
+	if d, ok := p.Dialect.(SchemaDialect); ok {
+		pk, err := p.primaryKey(d, b.Name)
+		if err != nil {
+			return err
+		}
+		if fmt.Sprint(pk) != fmt.Sprint(b.PrimaryKey) {
+			diffs = append(diffs, fmt.Sprintf("Primary Key %v instead of %v", pk, b.PrimaryKey))
+		}
+	}
+	if diffs != nil {
+		return SchemaMismatchError{b.Name, diffs}
+	}
+	return nil
+}
+
+// columnTypes returns the columns of a table.
+func (p *Row) columnTypes(tn r.TableName) ([]*sql.ColumnType, error) {
+	sb := &strings.Builder{}
+	sb.WriteString("SELECT * FROM ")
+	p.Dialect.WriteTableName(sb, tn)
+	sb.WriteString(" WHERE 1 = 0")
+	rows, err := p.DB.Query(sb.String())
+	if err != nil {
+		return nil, SqlError{err}
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, SqlError{err}
+	}
+	return cts, nil
+}
+
+// primaryKey returns the primary key of a table.
+func (p *Row) primaryKey(d SchemaDialect, tn r.TableName) (pk []r.FieldName, err error) {
+	sb := &strings.Builder{}
+	d.WritePrimaryKeyQuery(sb)
+	rows, err := p.DB.Query(sb.String(), string(tn))
+	if err != nil {
+		return nil, SqlError{err}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fn string
+		if err := rows.Scan(&fn); err != nil {
+			return nil, SqlError{err}
+		}
+		pk = append(pk, r.FieldName(fn))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, SqlError{err}
+	}
+	return
+}
+
+// VerifyTable implements TableVerifier. The Table is verified in every shard.
+func (p *ShardedRow) VerifyTable(b r.Table) error {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	errs := []error(nil)
+	for _, s := range p.Shards {
+		if err := s.VerifyTable(b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return h.MultipleErrors{Errors: errs}
+}
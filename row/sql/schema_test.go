@@ -0,0 +1,91 @@
+package heptane
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVerifyTable_OK(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT \* FROM "table1" WHERE 1 = 0`).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("foo").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("bar").OfType("text", ""),
+			sqlmock.NewColumn("baz").OfType("TEXT", ""),
+			sqlmock.NewColumn("qux").OfType("INT4", "")))
+	mock.ExpectQuery(`SELECT kcu.column_name FROM information_schema.table_constraints tc JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema\(\) AND tc.table_name = \$1 ORDER BY kcu.ordinal_position`).
+		WithArgs("table1").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("foo").AddRow("bar"))
+	rp := Row{DB: db, Dialect: Postgres{}, VerifyTables: true}
+	if err := rp.VerifyTable(TestingTable1()); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTable_Mismatch(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT \\* FROM `table1` WHERE 1 = 0").
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("foo").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("bar").OfType("INT", "")))
+	mock.ExpectQuery(`SELECT kcu.column_name FROM information_schema.table_constraints tc .* AND tc.table_schema = DATABASE\(\) AND tc.table_name = \? ORDER BY kcu.ordinal_position`).
+		WithArgs("table1").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("foo"))
+	rp := Row{DB: db, Dialect: MySQL{}, VerifyTables: true}
+	if err := rp.VerifyTable(TestingTable1()); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Schema Mismatch in Table table1: Column bar of Type INT incompatible with FieldType string, Missing Column for Field baz, Primary Key [foo] instead of [foo bar]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTable_WithoutSchemaDialect(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(`SELECT \* FROM 'table1' WHERE 1 = 0`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo", "bar", "baz"}))
+	rp := Row{DB: db, Dialect: TestDialect{}, VerifyTables: true}
+	if err := rp.VerifyTable(TestingTable1()); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTable_Disabled(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	rp := Row{DB: db, Dialect: Postgres{}}
+	if err := rp.VerifyTable(TestingTable1()); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
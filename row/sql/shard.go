@@ -0,0 +1,216 @@
+package heptane
+
+import (
+	"hash/fnv"
+	"sync"
+
+	rg "github.com/heptanes/heptane/ring"
+	r "github.com/heptanes/heptane/row"
+)
+
+// Router selects the shard of a partition.
+type Router interface {
+	// Route returns the index of the shard, lower than n, of the partition
+	// with the given encoded PartitionKey. Any other value is reported as
+	// an InvalidShardError.
+	Route(key []byte, n int) int
+}
+
+// ModuloRouter implements Router. The shard is the hash of the PartitionKey
+// modulo the number of shards.
+type ModuloRouter struct{}
+
+// Route implements Router.
+func (ModuloRouter) Route(key []byte, n int) int {
+	if n <= 0 {
+		return -1
+	}
+	h := fnv.New64a()
+	h.Write(key)
+	return int(h.Sum64() % uint64(n))
+}
+
+// RingRouter implements Router. The shard is selected by a consistent hashing
+// Ring that must contain one Node per shard, in the same order.
+type RingRouter struct {
+	Ring *rg.Ring
+}
+
+// Route implements Router.
+func (p RingRouter) Route(key []byte, n int) int {
+	return p.Ring.Node(key)
+}
+
+// ShardedRow implements RowProvider. Each RowAccess is performed on one of the
+// Shards, selected by the Router from the PartitionKey of the RowAccess.
+type ShardedRow struct {
+	// Shards contains the Row of each shard.
+	Shards []*Row
+	// Router selects the shard of each partition. A nil Router means a
+	// ModuloRouter.
+	Router Router
+
+	m          sync.RWMutex
+	resharding *Resharding
+}
+
+func accessFields(a r.RowAccess) (r.Table, r.FieldValuesByName, bool) {
+	switch a := a.(type) {
+	case r.RowCreate:
+		return a.Table, a.FieldValues, true
+	case *r.RowCreate:
+		return a.Table, a.FieldValues, true
+	case *r.RowRetrieve:
+		return a.Table, a.FieldValues, true
+	case r.RowUpdate:
+		return a.Table, a.FieldValues, true
+	case *r.RowUpdate:
+		return a.Table, a.FieldValues, true
+	case r.RowDelete:
+		return a.Table, a.FieldValues, true
+	case *r.RowDelete:
+		return a.Table, a.FieldValues, true
+	}
+	return r.Table{}, nil, false
+}
+
+func route(shards []*Row, router Router, t r.Table, fvn r.FieldValuesByName) (int, error) {
+	for _, fn := range t.PartitionKey {
+		if _, ok := fvn[fn]; !ok {
+			return 0, IncompletePartitionKeyError{t.Name, fn}
+		}
+	}
+	if len(shards) == 0 {
+		return 0, NoShardError{}
+	}
+	if router == nil {
+		router = ModuloRouter{}
+	}
+	i := router.Route(t.Encode(t.PartitionKey, fvn), len(shards))
+	if i < 0 || i >= len(shards) {
+		return 0, InvalidShardError{i, len(shards)}
+	}
+	return i, nil
+}
+
+// Shard returns the index of the shard of the partition given by the
+// PartitionKey contained in the FieldValuesByName.
+func (p *ShardedRow) Shard(t r.Table, fvn r.FieldValuesByName) (int, error) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return route(p.Shards, p.Router, t, fvn)
+}
+
+func (p *ShardedRow) shard(a r.RowAccess) (int, error) {
+	t, fvn, ok := accessFields(a)
+	if !ok {
+		return 0, UnsupportedRowAccessTypeError{a}
+	}
+	if err := t.Validate(); err != nil {
+		return 0, err
+	}
+	return route(p.Shards, p.Router, t, fvn)
+}
+
+// Access implements RowProvider.
+func (p *ShardedRow) Access(a r.RowAccess) error {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	i, err := p.shard(a)
+	if err != nil {
+		return err
+	}
+	if p.resharding != nil {
+		return p.resharding.access(p.Shards[i], a)
+	}
+	return p.Shards[i].Access(a)
+}
+
+// AccessSlice implements RowProvider. The RowAccesses of each shard are
+// performed concurrently with those of the other shards.
+func (p *ShardedRow) AccessSlice(aa []r.RowAccess) []error {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	errs := make([]error, len(aa))
+	type group struct {
+		ii []int
+		aa []r.RowAccess
+	}
+	groups := map[int]*group{}
+	for i, a := range aa {
+		s, err := p.shard(a)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		g := groups[s]
+		if g == nil {
+			g = &group{}
+			groups[s] = g
+		}
+		g.ii = append(g.ii, i)
+		g.aa = append(g.aa, a)
+	}
+	wg := sync.WaitGroup{}
+	for s, g := range groups {
+		wg.Add(1)
+		go func(s int, g *group) {
+			defer wg.Done()
+			if p.resharding != nil {
+				for j, err := range p.resharding.accessSlice(p.Shards[s], g.aa) {
+					errs[g.ii[j]] = err
+				}
+				return
+			}
+			for j, err := range p.Shards[s].AccessSlice(g.aa) {
+				errs[g.ii[j]] = err
+			}
+		}(s, g)
+	}
+	wg.Wait()
+	return errs
+}
+
+// ScanPartitions implements PartitionScanner. The shards are scanned
+// concurrently.
+func (p *ShardedRow) ScanPartitions(t r.Table) ([]r.FieldValuesByName, error) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return scanPartitions(p.Shards, t)
+}
+
+// InvalidateTable implements TableInvalidator.
+func (p *ShardedRow) InvalidateTable(tn r.TableName) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	for _, s := range p.Shards {
+		s.InvalidateTable(tn)
+	}
+	if p.resharding != nil {
+		for _, s := range p.resharding.Shards {
+			s.InvalidateTable(tn)
+		}
+	}
+}
+
+func scanPartitions(shards []*Row, t r.Table) ([]r.FieldValuesByName, error) {
+	results := make([][]r.FieldValuesByName, len(shards))
+	errs := make([]error, len(shards))
+	wg := sync.WaitGroup{}
+	for i, s := range shards {
+		wg.Add(1)
+		go func(i int, s *Row) {
+			defer wg.Done()
+			results[i], errs[i] = s.ScanPartitions(t)
+		}(i, s)
+	}
+	wg.Wait()
+	fvns := []r.FieldValuesByName(nil)
+	for i := range shards {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		fvns = append(fvns, results[i]...)
+	}
+	return fvns, nil
+}
@@ -0,0 +1,199 @@
+package heptane
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	rg "github.com/heptanes/heptane/ring"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestingShards(t *testing.T, n int) (*ShardedRow, []sqlmock.Sqlmock, func()) {
+	p := &ShardedRow{}
+	mocks := []sqlmock.Sqlmock{}
+	dbs := []*sql.DB{}
+	for i := 0; i < n; i++ {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Shards = append(p.Shards, &Row{DB: db, Dialect: TestDialect{}})
+		mocks = append(mocks, mock)
+		dbs = append(dbs, db)
+	}
+	return p, mocks, func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+}
+
+func TestModuloRouter(t *testing.T) {
+	counts := make([]int, 3)
+	for i := 0; i < 300; i++ {
+		counts[ModuloRouter{}.Route([]byte(fmt.Sprint(i)), 3)]++
+	}
+	for i, c := range counts {
+		if c < 50 {
+			t.Error(i, c)
+		}
+	}
+}
+
+func TestShardedRow_IncompletePartitionKey(t *testing.T) {
+	p, _, done := TestingShards(t, 2)
+	defer done()
+	a := &r.RowRetrieve{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"bar": "2"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Incomplete PartitionKey for Table table1: Missing FieldValue for Field foo` {
+		t.Error(s)
+	}
+}
+
+func TestShardedRow_UnsupportedRowAccessTypeError(t *testing.T) {
+	p, _, done := TestingShards(t, 2)
+	defer done()
+	if err := p.Access(r.RowRetrieve{}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported RowAccess Type: heptane.RowRetrieve{Table:heptane.Table{Name:"", PartitionKey:[]heptane.FieldName(nil), PrimaryKey:[]heptane.FieldName(nil), Values:[]heptane.FieldName(nil), Types:heptane.FieldTypesByName(nil), PrimaryKeyCachePrefix:[]string(nil)}, FieldValues:heptane.FieldValuesByName(nil), RetrievedValues:[]heptane.FieldValuesByName(nil), Consistent:false}` {
+		t.Error(s)
+	}
+}
+
+func TestShardedRow_NoShard(t *testing.T) {
+	p := &ShardedRow{}
+	a := r.RowDelete{Table: TestingTable1(), FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `No Shard in ShardedRow` {
+		t.Error(s)
+	}
+	if errs := p.AccessSlice([]r.RowAccess{a}); errs[0] == nil {
+		t.Error(errs[0])
+	} else if _, ok := errs[0].(NoShardError); !ok {
+		t.Error(errs[0])
+	}
+}
+
+func TestShardedRow_InvalidShard(t *testing.T) {
+	p, _, done := TestingShards(t, 2)
+	defer done()
+	b := TestingTable1()
+	// The Ring has more Nodes than Shards.
+	p.Router = RingRouter{rg.New([]rg.Node{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 0)}
+	invalid := 0
+	for i := 0; i < 30; i++ {
+		i, err := p.Shard(b, r.FieldValuesByName{"foo": fmt.Sprint(i)})
+		if err == nil && (i < 0 || i >= 2) {
+			t.Error(i)
+		} else if _, ok := err.(InvalidShardError); ok {
+			invalid++
+		} else if err != nil {
+			t.Error(err)
+		}
+	}
+	if invalid == 0 {
+		t.Error(invalid)
+	}
+	p.Router = RingRouter{rg.New(nil, 0)}
+	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := p.Access(a); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Invalid Shard -1 of 2 Shards` {
+		t.Error(s)
+	}
+	if errs := p.AccessSlice([]r.RowAccess{a}); errs[0] == nil {
+		t.Error(errs[0])
+	}
+}
+
+func TestShardedRow_ValidationError(t *testing.T) {
+	p, _, done := TestingShards(t, 2)
+	defer done()
+	b := TestingTable1()
+	b.Name = ""
+	if err := p.Access(r.RowDelete{Table: b}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Empty TableName in Table` {
+		t.Error(s)
+	}
+}
+
+func TestShardedRow_Access(t *testing.T) {
+	p, mocks, done := TestingShards(t, 2)
+	defer done()
+	b := TestingTable1()
+	fvn := r.FieldValuesByName{"foo": "1", "bar": "2"}
+	s, err := p.Shard(b, fvn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mocks[s].ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := p.Access(r.RowDelete{Table: b, FieldValues: fvn}); err != nil {
+		t.Error(err)
+	}
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShardedRow_AccessSlice(t *testing.T) {
+	p, mocks, done := TestingShards(t, 2)
+	defer done()
+	p.Router = RingRouter{rg.New([]rg.Node{{Name: "a"}, {Name: "b"}}, 0)}
+	b := TestingTable1()
+	aa := []r.RowAccess{}
+	shards := map[int]bool{}
+	for i := 0; len(shards) < 2; i++ {
+		fvn := r.FieldValuesByName{"foo": fmt.Sprint(i), "bar": "2"}
+		s, err := p.Shard(b, fvn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shards[s] {
+			continue
+		}
+		shards[s] = true
+		mocks[s].ExpectExec(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+			WithArgs(fmt.Sprint(i), "2").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		aa = append(aa, r.RowDelete{Table: b, FieldValues: fvn})
+	}
+	aa = append(aa, r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{}})
+	errs := p.AccessSlice(aa)
+	if l := len(errs); l != 3 {
+		t.Fatal(l)
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Error(errs)
+	}
+	if s := errs[2].Error(); s != `Incomplete PartitionKey for Table table1: Missing FieldValue for Field foo` {
+		t.Error(s)
+	}
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShardedRow_ScanPartitions(t *testing.T) {
+	p, mocks, done := TestingShards(t, 2)
+	defer done()
+	mocks[0].ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("1"))
+	mocks[1].ExpectQuery(`SELECT DISTINCT 'foo' FROM 'table1'`).
+		WillReturnRows(sqlmock.NewRows([]string{"foo"}).AddRow("2"))
+	if fvns, err := p.ScanPartitions(TestingTable1()); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprint(fvns); s != `[map[foo:1] map[foo:2]]` {
+		t.Error(s)
+	}
+}
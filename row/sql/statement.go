@@ -0,0 +1,116 @@
+package heptane
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// statement is a cached sql string with its prepared statements.
+type statement struct {
+	signature string
+	tableName r.TableName
+	query     string
+	stmts     map[*sql.DB]*sql.Stmt
+	// refs is the number of executions in progress.
+	refs int
+	// evicted means the statement is no longer cached and its prepared
+	// statements must be closed once refs reaches zero.
+	evicted bool
+}
+
+// statementCache keeps the most recently used statements.
+type statementCache struct {
+	m       sync.Mutex
+	lru     list.List
+	entries map[string]*list.Element
+}
+
+// statement returns the sql string with the given signature, calling build
+// only if it is not cached, and its prepared statement on the given database,
+// or nil if it must be executed unprepared. The returned function must be
+// called once the execution is done.
+func (p *Row) statement(db *sql.DB, tn r.TableName, sig string, build func() string) (string, *sql.Stmt, func()) {
+	if p.Statements <= 0 {
+		return build(), nil, func() {}
+	}
+	c := &p.statements
+	c.m.Lock()
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+	}
+	var s *statement
+	if e, ok := c.entries[sig]; ok {
+		c.lru.MoveToFront(e)
+		s = e.Value.(*statement)
+	} else {
+		s = &statement{signature: sig, tableName: tn, query: build(), stmts: map[*sql.DB]*sql.Stmt{}}
+		c.entries[sig] = c.lru.PushFront(s)
+		for c.lru.Len() > p.Statements {
+			c.evict(c.lru.Back())
+		}
+	}
+	s.refs++
+	stmt := s.stmts[db]
+	c.m.Unlock()
+	release := func() {
+		c.m.Lock()
+		defer c.m.Unlock()
+		s.refs--
+		s.close()
+	}
+	if stmt != nil {
+		return s.query, stmt, release
+	}
+	prepared, err := db.Prepare(s.query)
+	if err != nil {
+		// The error, if permanent, is reported by the unprepared execution.
+		return s.query, nil, release
+	}
+	c.m.Lock()
+	if stmt = s.stmts[db]; stmt == nil {
+		s.stmts[db] = prepared
+		stmt = prepared
+	} else {
+		prepared.Close()
+	}
+	c.m.Unlock()
+	return s.query, stmt, release
+}
+
+// evict removes a statement from the cache. Called with the lock held.
+func (c *statementCache) evict(e *list.Element) {
+	s := c.lru.Remove(e).(*statement)
+	delete(c.entries, s.signature)
+	s.evicted = true
+	s.close()
+}
+
+// close closes the prepared statements of an evicted statement that is no
+// longer executed. Called with the lock held.
+func (s *statement) close() {
+	if !s.evicted || s.refs > 0 {
+		return
+	}
+	for db, stmt := range s.stmts {
+		stmt.Close()
+		delete(s.stmts, db)
+	}
+}
+
+// InvalidateTable implements TableInvalidator. It discards the cached sql
+// strings and prepared statements of the table.
+func (p *Row) InvalidateTable(tn r.TableName) {
+	c := &p.statements
+	c.m.Lock()
+	defer c.m.Unlock()
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*statement).tableName == tn {
+			c.evict(e)
+		}
+		e = next
+	}
+}
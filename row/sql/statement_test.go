@@ -0,0 +1,182 @@
+package heptane
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestStatements_Reuse(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	prep := mock.ExpectPrepare(`INSERT INTO 'table1' \('foo', 'bar', 'baz'\) VALUES \(\?, \?, \?\)`).WillBeClosed()
+	prep.ExpectExec().WithArgs("1", "2", "3").WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs("4", "5", "6").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		ExpectQuery().WithArgs("1", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: 1}
+	for _, fvn := range []r.FieldValuesByName{
+		{"foo": "1", "bar": "2", "baz": "3"},
+		{"foo": "4", "bar": "5", "baz": "6"},
+	} {
+		if err := rp.Access(r.RowCreate{Table: b, FieldValues: fvn}); err != nil {
+			t.Error(err)
+		}
+	}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatements_InvalidateTable(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	for i := 0; i < 2; i++ {
+		mock.ExpectPrepare(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).WillBeClosed().
+			ExpectExec().WithArgs("1", "2").WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: 10}
+	a := r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	rp.InvalidateTable("table2")
+	rp.InvalidateTable(b.Name)
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	rp.InvalidateTable(b.Name)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatements_PrepareError(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectPrepare(`UPDATE 'table1' SET 'baz' = \? WHERE 'foo' = \? AND 'bar' = \?`).
+		WillReturnError(io.ErrUnexpectedEOF)
+	mock.ExpectExec(`UPDATE 'table1' SET 'baz' = \? WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("3", "1", "2").WillReturnResult(sqlmock.NewResult(1, 1))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: 10}
+	a := r.RowUpdate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}
+	if err := rp.Access(a); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatements_TableVersions(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectPrepare(`INSERT INTO 'table1' \('foo', 'bar', 'baz'\) VALUES \(\?, \?, \?\)`).
+		ExpectExec().WithArgs("1", "2", "3").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare(`INSERT INTO 'table1' \('foo', 'bar', 'qux'\) VALUES \(\?, \?, \?\)`).
+		ExpectExec().WithArgs("1", "2", "3").WillReturnResult(sqlmock.NewResult(1, 1))
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: 10}
+	b := TestingTable1()
+	if err := rp.Access(r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}}); err != nil {
+		t.Error(err)
+	}
+	// Another version of the Table with the same Name.
+	b = TestingTable1()
+	b.Values = []r.FieldName{"qux"}
+	b.Types = r.FieldTypesByName{"foo": "string", "bar": "string", "qux": "string"}
+	if err := rp.Access(r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "qux": "3"}}); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// BenchDriver is a database/sql driver that accepts every statement and
+// returns no rows. It measures the overhead of Row and database/sql only, the
+// gain of the prepared statements against a real database is larger.
+type BenchDriver struct{}
+
+func (BenchDriver) Open(string) (driver.Conn, error) { return BenchConn{}, nil }
+
+type BenchConn struct{}
+
+func (BenchConn) Prepare(string) (driver.Stmt, error) { return BenchStmt{}, nil }
+func (BenchConn) Close() error                        { return nil }
+func (BenchConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type BenchStmt struct{}
+
+func (BenchStmt) Close() error                               { return nil }
+func (BenchStmt) NumInput() int                              { return -1 }
+func (BenchStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (BenchStmt) Query([]driver.Value) (driver.Rows, error)  { return BenchRows{}, nil }
+
+type BenchRows struct{}
+
+func (BenchRows) Columns() []string         { return []string{"baz"} }
+func (BenchRows) Close() error              { return nil }
+func (BenchRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("heptane-bench", BenchDriver{})
+}
+
+func benchmarkAccess(b *testing.B, statements int) {
+	db, err := sql.Open("heptane-bench", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: statements}
+	t := TestingTable1()
+	aa := []r.RowAccess{
+		r.RowCreate{Table: t, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}},
+		&r.RowRetrieve{Table: t, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowUpdate{Table: t, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "4"}},
+		r.RowDelete{Table: t, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := rp.Access(aa[i%len(aa)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAccess_Unprepared(b *testing.B) {
+	benchmarkAccess(b, 0)
+}
+
+func BenchmarkAccess_Prepared(b *testing.B) {
+	benchmarkAccess(b, 16)
+}
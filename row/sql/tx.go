@@ -0,0 +1,42 @@
+package heptane
+
+import (
+	"context"
+	"database/sql"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+// AccessSliceTx performs several RowAccesses in order in a single transaction
+// on the primary database, started with the given options. The RowAccesses are
+// never coalesced. The first failing RowAccess rolls the transaction back: its
+// error is reported for it and a RolledBackError for every other RowAccess.
+func (p *Row) AccessSliceTx(aa []r.RowAccess, opts *sql.TxOptions) (errs []error) {
+	errs = make([]error, len(aa))
+	if len(aa) == 0 {
+		return
+	}
+	tx, err := p.DB.BeginTx(context.Background(), opts)
+	if err != nil {
+		for i := range errs {
+			errs[i] = SqlError{err}
+		}
+		return
+	}
+	for i, a := range aa {
+		if err := p.access(tx, a); err != nil {
+			tx.Rollback()
+			for j := range errs {
+				errs[j] = RolledBackError{i}
+			}
+			errs[i] = err
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		for i := range errs {
+			errs[i] = SqlError{err}
+		}
+	}
+	return
+}
@@ -0,0 +1,129 @@
+package heptane
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestAccessSliceTx_Commit(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT 'baz' FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		WithArgs("1", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"baz"}).AddRow("3"))
+	mock.ExpectCommit()
+	replica, mock1, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer replica.Close()
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Replicas: []*sql.DB{replica}, BatchSize: 10}
+	a := &r.RowRetrieve{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}}
+	errs := rp.AccessSliceTx([]r.RowAccess{
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		a,
+	}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if s := fmt.Sprint(errs); s != `[<nil> <nil>]` {
+		t.Error(s)
+	}
+	if s := fmt.Sprintf("%#v", a.RetrievedValues); s != `[]heptane.FieldValuesByName{heptane.FieldValuesByName{"bar":"2", "baz":"3", "foo":"1"}}` {
+		t.Error(s)
+	}
+	for _, m := range []sqlmock.Sqlmock{mock, mock1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestAccessSliceTx_Rollback(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO 'table1' \('foo', 'bar'\) VALUES \(\?, \?\)`).
+		WithArgs("1", "3").
+		WillReturnError(errors.New("problem"))
+	mock.ExpectRollback()
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Transaction: &sql.TxOptions{}}
+	errs := rp.AccessSlice([]r.RowAccess{
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "3"}},
+		r.RowCreate{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "4"}},
+	})
+	if s := fmt.Sprint(errs); s != `[Transaction rolled back by the error of RowAccess 1 Sql Error: problem Transaction rolled back by the error of RowAccess 1]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSliceTx_BeginError(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin().WillReturnError(errors.New("problem"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}}
+	errs := rp.AccessSliceTx([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+	}, nil)
+	if s := fmt.Sprint(errs); s != `[Sql Error: problem]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessSliceTx_CommitError(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	// Prepared on the database and then on the connection of the transaction.
+	mock.ExpectPrepare(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`)
+	mock.ExpectPrepare(`DELETE FROM 'table1' WHERE 'foo' = \? AND 'bar' = \?`).
+		ExpectExec().WithArgs("1", "2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(errors.New("problem"))
+	b := TestingTable1()
+	rp := Row{DB: db, Dialect: TestDialect{}, Statements: 10}
+	errs := rp.AccessSliceTx([]r.RowAccess{
+		r.RowDelete{Table: b, FieldValues: r.FieldValuesByName{"foo": "1", "bar": "2"}},
+	}, nil)
+	if s := fmt.Sprint(errs); s != `[Sql Error: problem]` {
+		t.Error(s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
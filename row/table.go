@@ -1,75 +1,111 @@
 package heptane
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
 
 // Validate checks there are no inconsistencies in the definition of the Table.
+// The first one found is returned as a TableValidationError.
 func (t Table) Validate() error {
 	if len(t.Name) == 0 {
-		return fmt.Errorf("Empty TableName in Table")
+		return TableValidationError{Reason: ErrEmptyTableName}
 	}
 	if len(t.PartitionKey) == 0 {
-		return fmt.Errorf("Table %v: Missing PartitionKey", t.Name)
+		return TableValidationError{TableName: t.Name, Reason: ErrMissingPartitionKey}
 	}
 	for i, fn := range t.PartitionKey {
 		if len(fn) == 0 {
-			return fmt.Errorf("Table %v: Empty FieldName in PartitionKey", t.Name)
+			return TableValidationError{TableName: t.Name, Reason: ErrEmptyPartitionKeyField}
 		}
 		for _, fn2 := range t.PartitionKey[:i] {
 			if fn2 == fn {
-				return fmt.Errorf("Table %v: Repeated FieldName in PartitionKey: %v", t.Name, fn)
+				return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrRepeatedPartitionKeyField}
 			}
 		}
 	}
 	if len(t.PrimaryKey) == 0 {
-		return fmt.Errorf("Table %v: Missing PrimaryKey", t.Name)
+		return TableValidationError{TableName: t.Name, Reason: ErrMissingPrimaryKey}
 	}
 	for i, fn := range t.PrimaryKey {
 		if len(fn) == 0 {
-			return fmt.Errorf("Table %v: Empty FieldName in PrimaryKey", t.Name)
+			return TableValidationError{TableName: t.Name, Reason: ErrEmptyPrimaryKeyField}
 		}
 		if i < len(t.PartitionKey) {
 			if fn2 := t.PartitionKey[i]; fn != fn2 {
-				return fmt.Errorf("Table %v: Mismatched PrimaryKey: %v", t.Name, fn)
+				return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrMismatchedPrimaryKey}
 			}
 		}
 		for _, fn2 := range t.PrimaryKey[:i] {
 			if fn2 == fn {
-				return fmt.Errorf("Table %v: Repeated FieldName in PrimaryKey: %v", t.Name, fn)
+				return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrRepeatedPrimaryKeyField}
 			}
 		}
 	}
 	for i, fn := range t.Values {
 		if len(fn) == 0 {
-			return fmt.Errorf("Table %v: Empty FieldName in Values", t.Name)
+			return TableValidationError{TableName: t.Name, Reason: ErrEmptyValuesField}
 		}
 		for _, fn2 := range t.PrimaryKey {
 			if fn2 == fn {
-				return fmt.Errorf("Table %v: Repeated FieldName in Values: %v", t.Name, fn)
+				return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrRepeatedValuesField}
 			}
 		}
 		for _, fn2 := range t.Values[:i] {
 			if fn2 == fn {
-				return fmt.Errorf("Table %v: Repeated FieldName in Values: %v", t.Name, fn)
+				return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrRepeatedValuesField}
 			}
 		}
 	}
 	for _, fn := range t.PrimaryKey {
 		ft, ok := t.Types[fn]
 		if !ok {
-			return fmt.Errorf("Table %v: Missing FieldType for FieldName: %v", t.Name, fn)
+			return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrMissingFieldType}
 		}
 		if ft != "string" && ft != "bool" {
-			return fmt.Errorf("Table %v: Invalid FieldType for FieldName %v: %v", t.Name, fn, ft)
+			return TableValidationError{TableName: t.Name, FieldName: fn, FieldType: ft, Reason: ErrInvalidFieldType}
 		}
 	}
 	for _, fn := range t.Values {
 		ft, ok := t.Types[fn]
 		if !ok {
-			return fmt.Errorf("Table %v: Missing FieldType for FieldName: %v", t.Name, fn)
+			return TableValidationError{TableName: t.Name, FieldName: fn, Reason: ErrMissingFieldType}
 		}
 		if ft != "string" && ft != "bool" {
-			return fmt.Errorf("Table %v: Invalid FieldType for FieldName %v: %v", t.Name, fn, ft)
+			return TableValidationError{TableName: t.Name, FieldName: fn, FieldType: ft, Reason: ErrInvalidFieldType}
 		}
 	}
 	return nil
 }
+
+// Encode returns a binary representation of the values of the given fields.
+// Equal values produce equal representations, so the result may be hashed,
+// compared or used as a map key. A missing field is represented as a nil
+// value.
+func (t Table) Encode(fns []FieldName, fvn FieldValuesByName) []byte {
+	b := bytes.Buffer{}
+	q := make([]byte, binary.MaxVarintLen64)
+	for _, fn := range fns {
+		switch fv := fvn[fn].(type) {
+		case nil:
+			b.WriteByte('n')
+		case bool:
+			if fv {
+				b.WriteByte('t')
+			} else {
+				b.WriteByte('f')
+			}
+		case string:
+			b.WriteByte('s')
+			b.Write(q[:binary.PutUvarint(q, uint64(len(fv)))])
+			b.WriteString(fv)
+		default:
+			s := fmt.Sprintf("%#v", fv)
+			b.WriteByte('x')
+			b.Write(q[:binary.PutUvarint(q, uint64(len(s)))])
+			b.WriteString(s)
+		}
+	}
+	return b.Bytes()
+}
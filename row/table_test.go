@@ -2,6 +2,7 @@ package heptane
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -205,6 +206,20 @@ func TestTable_Validate_Types_Invalid_Values(t *testing.T) {
 	}
 }
 
+func TestTable_Validate_Reason(t *testing.T) {
+	b := TestingTable()
+	b.Types["baz"] = "int"
+	err := b.Validate()
+	if !errors.Is(err, ErrInvalidFieldType) {
+		t.Error(err)
+	}
+	if e := (TableValidationError{}); !errors.As(err, &e) {
+		t.Error(err)
+	} else if e.TableName != "table" || e.FieldName != "baz" || e.FieldType != "int" {
+		t.Error(e)
+	}
+}
+
 func TestTable_Validate_OK(t *testing.T) {
 	b := TestingTable()
 	if err := b.Validate(); err != nil {
@@ -227,3 +242,28 @@ func TestTable_Validate_ValidType_Bool(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTable_Encode(t *testing.T) {
+	b := TestingTable()
+	if s := string(b.Encode(b.PrimaryKey, FieldValuesByName{"foo": "1", "bar": "23"})); s != "s\x011s\x0223" {
+		t.Error(s)
+	}
+	if s := string(b.Encode(b.PrimaryKey, FieldValuesByName{"foo": true, "bar": false})); s != "tf" {
+		t.Error(s)
+	}
+	if s := string(b.Encode(b.PrimaryKey, FieldValuesByName{"foo": nil})); s != "nn" {
+		t.Error(s)
+	}
+	if s := string(b.Encode(b.PartitionKey, FieldValuesByName{"foo": 1})); s != "x\x011" {
+		t.Error(s)
+	}
+}
+
+func TestTable_Encode_Unambiguous(t *testing.T) {
+	b := TestingTable()
+	x := b.Encode(b.PrimaryKey, FieldValuesByName{"foo": "1s", "bar": ""})
+	y := b.Encode(b.PrimaryKey, FieldValuesByName{"foo": "1", "bar": "s"})
+	if string(x) == string(y) {
+		t.Error(string(x))
+	}
+}
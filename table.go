@@ -179,3 +179,23 @@ func encode(t r.Table, kv r.FieldValuesByName, cv cacheValue) (r.FieldValuesByNa
 	}
 	return fvn, nil
 }
+
+// CacheKey returns the CacheKey of the row with the given PrimaryKey in the
+// PrimaryKey cache of the Table.
+func CacheKey(t r.Table, fvn r.FieldValuesByName) (c.CacheKey, error) {
+	key, err := decodePrimaryKey(t, fvn)
+	if err != nil {
+		return "", err
+	}
+	return key.key(), nil
+}
+
+// CacheValue returns the CacheValue of the row with the given Values in the
+// PrimaryKey cache of the Table.
+func CacheValue(t r.Table, fvn r.FieldValuesByName) (c.CacheValue, error) {
+	value, err := decodeValue(t, fvn)
+	if err != nil {
+		return nil, err
+	}
+	return value.value(), nil
+}
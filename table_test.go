@@ -0,0 +1,44 @@
+package heptane
+
+import (
+	"fmt"
+	"testing"
+
+	r "github.com/heptanes/heptane/row"
+)
+
+func TestCacheKey_OK(t *testing.T) {
+	b := TestingTable1()
+	if k, err := CacheKey(b, r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}); err != nil {
+		t.Error(err)
+	} else if k != "table1_pk#0#s1#s2" {
+		t.Error(k)
+	}
+}
+
+func TestCacheKey_MissingPrimaryKey(t *testing.T) {
+	b := TestingTable1()
+	if _, err := CacheKey(b, r.FieldValuesByName{"foo": "1"}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Missing FieldValue for Field table1.bar: map[foo:1]` {
+		t.Error(s)
+	}
+}
+
+func TestCacheValue_OK(t *testing.T) {
+	b := TestingTable1()
+	if v, err := CacheValue(b, r.FieldValuesByName{"foo": "1", "bar": "2", "baz": "3"}); err != nil {
+		t.Error(err)
+	} else if s := fmt.Sprintf("%#v", v); s != `heptane.CacheValue{0x73, 0x33}` {
+		t.Error(s)
+	}
+}
+
+func TestCacheValue_InvalidValue(t *testing.T) {
+	b := TestingTable1()
+	if _, err := CacheValue(b, r.FieldValuesByName{"baz": 3}); err == nil {
+		t.Error(err)
+	} else if s := err.Error(); s != `Unsupported FieldValue for FieldType string: 3` {
+		t.Error(s)
+	}
+}